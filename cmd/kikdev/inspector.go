@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// payload is a single webhook request as seen by kikdev.
+type payload struct {
+	ReceivedAt     time.Time            `json:"receivedAt"`
+	Username       string               `json:"username"`
+	Signature      string               `json:"signature"`
+	ValidSignature bool                 `json:"validSignature"`
+	Body           json.RawMessage      `json:"body"`
+	Messages       kik.ReceivedMessages `json:"messages"`
+	DecodeError    string               `json:"decodeError,omitempty"`
+	Forward        string               `json:"forward,omitempty"`
+}
+
+// inspector keeps the most recent payloads in memory, newest first.
+type inspector struct {
+	mu       sync.Mutex
+	size     int
+	payloads []payload
+}
+
+func newInspector(size int) *inspector {
+	if size < 1 {
+		size = 1
+	}
+	return &inspector{size: size}
+}
+
+func (i *inspector) add(p payload) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.payloads = append([]payload{p}, i.payloads...)
+	if len(i.payloads) > i.size {
+		i.payloads = i.payloads[:i.size]
+	}
+}
+
+func (i *inspector) recent() []payload {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	out := make([]payload, len(i.payloads))
+	copy(out, i.payloads)
+	return out
+}
+
+// ServeHTTP lists recent payloads as JSON.
+func (i *inspector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(i.recent()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func (i *inspector) serveHTML(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+
+	type row struct {
+		payload
+		Pretty string
+	}
+	var rows []row
+	for _, p := range i.recent() {
+		pretty, err := json.MarshalIndent(p.Body, "", "  ")
+		if err != nil {
+			pretty = p.Body
+		}
+		rows = append(rows, row{p, string(pretty)})
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := inspectorTemplate.Execute(w, rows); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+var inspectorTemplate = template.Must(template.New("inspector").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>kikdev</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+pre { background: #f4f4f4; padding: 1em; overflow-x: auto; }
+.invalid { color: #c00; }
+</style>
+</head>
+<body>
+<h1>Recent Kik payloads</h1>
+<p><a href="/payloads">JSON</a></p>
+{{range .}}
+<h3>{{.ReceivedAt.Format "2006-01-02 15:04:05"}} &mdash; {{.Username}}
+{{if .ValidSignature}}(signature valid){{else}}<span class="invalid">(signature invalid)</span>{{end}}</h3>
+{{if .DecodeError}}<p class="invalid">decode error: {{.DecodeError}}</p>{{end}}
+{{if .Forward}}<p>forwarded: {{.Forward}}</p>{{end}}
+<pre>{{.Pretty}}</pre>
+{{else}}
+<p>Nothing received yet.</p>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,165 @@
+// Command kikdev runs a local webhook receiver for developing Kik bots.
+//
+// Every inbound payload has its signature checked with VerifySignature, is decoded into
+// kik.ReceivedMessages and pretty-printed to stdout. Recent payloads can be browsed at /
+// (HTML) or /payloads (JSON), and each payload can optionally be forwarded to a bot running
+// on a local port.
+//
+//	KIKBOT_USERNAME=mybot KIKBOT_API_KEY=... kikdev -addr :8080 -forward http://localhost:3000/incoming
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// maxBodyBytes caps webhook requests, Kik's payloads are a few kilobytes.
+const maxBodyBytes = 1 << 20
+
+func main() {
+	addr := flag.String("addr", ":8080", "address to listen on")
+	path := flag.String("path", "/incoming", "path Kik delivers webhooks to")
+	username := flag.String("username", os.Getenv("KIKBOT_USERNAME"), "bot username, defaults to $KIKBOT_USERNAME")
+	key := flag.String("key", os.Getenv("KIKBOT_API_KEY"), "bot API key, defaults to $KIKBOT_API_KEY")
+	forward := flag.String("forward", "", "optional URL of a local bot to forward payloads to")
+	history := flag.Int("history", 50, "number of recent payloads kept for the inspector")
+	insecure := flag.Bool("insecure", false, "accept payloads with invalid signatures")
+	flag.Parse()
+
+	if *key == "" && !*insecure {
+		log.Fatal("no API key set, use -key, $KIKBOT_API_KEY or -insecure")
+	}
+
+	client, err := kik.NewKikClient("https://api.kik.com/", *username, *key, nil)
+	if err != nil {
+		log.Fatalf("could not initiate client: %v ", err)
+	}
+
+	r := &receiver{
+		client:    client,
+		inspector: newInspector(*history),
+		forward:   *forward,
+		insecure:  *insecure,
+		http:      &http.Client{Timeout: 10 * time.Second},
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*path, r)
+	mux.Handle("/payloads", r.inspector)
+	mux.HandleFunc("/", r.inspector.serveHTML)
+
+	log.Printf("kikdev listening on %s, webhook path %s", *addr, *path)
+	log.Fatal(http.ListenAndServe(*addr, mux))
+}
+
+// receiver handles webhook requests from Kik.
+type receiver struct {
+	client    *kik.Client
+	inspector *inspector
+	forward   string
+	insecure  bool
+	http      *http.Client
+}
+
+func (rv *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxBodyBytes))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	p := payload{
+		ReceivedAt: time.Now(),
+		Username:   r.Header.Get(kik.UsernameHeader),
+		Signature:  r.Header.Get(kik.SignatureHeader),
+		Body:       rawJSON(body),
+	}
+	p.ValidSignature = rv.client.VerifySignature(p.Signature, body)
+
+	var messages kik.ReceivedMessages
+	if err := json.Unmarshal(body, &messages); err != nil {
+		p.DecodeError = err.Error()
+	}
+	p.Messages = messages
+
+	if rv.forward != "" && (p.ValidSignature || rv.insecure) {
+		p.Forward = rv.forwardPayload(r, body)
+	}
+
+	rv.inspector.add(p)
+	printPayload(p)
+
+	switch {
+	case !p.ValidSignature && !rv.insecure:
+		http.Error(w, "invalid signature", http.StatusForbidden)
+	case p.DecodeError != "":
+		http.Error(w, p.DecodeError, http.StatusBadRequest)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// forwardPayload replays the original request against the local bot and returns a short status.
+func (rv *receiver) forwardPayload(r *http.Request, body []byte) string {
+	req, err := http.NewRequest(http.MethodPost, rv.forward, bytes.NewReader(body))
+	if err != nil {
+		return err.Error()
+	}
+	for _, h := range []string{"Content-Type", kik.UsernameHeader, kik.SignatureHeader} {
+		if v := r.Header.Get(h); v != "" {
+			req.Header.Set(h, v)
+		}
+	}
+
+	resp, err := rv.http.Do(req)
+	if err != nil {
+		return err.Error()
+	}
+	defer resp.Body.Close()
+	return resp.Status
+}
+
+func printPayload(p payload) {
+	sig := "valid"
+	if !p.ValidSignature {
+		sig = "INVALID"
+	}
+	fmt.Printf("--- %s from bot %q, signature %s\n", p.ReceivedAt.Format(time.RFC3339), p.Username, sig)
+	if p.DecodeError != "" {
+		fmt.Printf("decode error: %s\n", p.DecodeError)
+	}
+	for _, m := range p.Messages {
+		out, err := json.MarshalIndent(m, "", "  ")
+		if err != nil {
+			fmt.Printf("could not print %T: %v\n", m, err)
+			continue
+		}
+		fmt.Printf("%T\n%s\n", m, out)
+	}
+	if p.Forward != "" {
+		fmt.Printf("forwarded: %s\n", p.Forward)
+	}
+}
+
+// rawJSON keeps valid JSON bodies as-is and quotes anything else so the inspector can still encode it.
+func rawJSON(body []byte) json.RawMessage {
+	if json.Valid(body) {
+		return body
+	}
+	quoted, _ := json.Marshal(string(body))
+	return quoted
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+const textPayload = `{"messages": [{"type": "text", "chatId": "c1", "id": "m1", "from": "alice", "body": "hi"}]}`
+
+// testReceiver returns a receiver forwarding to a local bot, and the requests that bot received.
+func testReceiver(t *testing.T, insecure bool) (*receiver, func() []*http.Request, func()) {
+	client, err := kik.NewKikClient("https://api.kik.com/", "mybot", "key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var forwarded []*http.Request
+	bot := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		r.Body = ioutil.NopCloser(strings.NewReader(string(body)))
+		mu.Lock()
+		forwarded = append(forwarded, r)
+		mu.Unlock()
+	}))
+
+	rv := &receiver{
+		client:    client,
+		inspector: newInspector(2),
+		forward:   bot.URL + "/incoming",
+		insecure:  insecure,
+		http:      &http.Client{Timeout: time.Second},
+	}
+	return rv, func() []*http.Request {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]*http.Request(nil), forwarded...)
+	}, bot.Close
+}
+
+func TestReceiver_ChecksSignatureAndForwards(t *testing.T) {
+	rv, forwarded, teardown := testReceiver(t, false)
+	defer teardown()
+
+	signed := kiktest.WebhookRequest(rv.client, textPayload)
+	forged := kiktest.WebhookRequest(rv.client, textPayload)
+	forged.Header.Set(kik.SignatureHeader, "0000")
+	badJSON := kiktest.WebhookRequest(rv.client, "{not json")
+	tooLarge := kiktest.WebhookRequest(rv.client, strings.Repeat(" ", maxBodyBytes+1))
+	get := httptest.NewRequest(http.MethodGet, "/incoming", nil)
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		want int
+	}{
+		{"signed", signed, http.StatusOK},
+		{"forged", forged, http.StatusForbidden},
+		{"bad JSON", badJSON, http.StatusBadRequest},
+		{"too large", tooLarge, http.StatusRequestEntityTooLarge},
+		{"GET", get, http.StatusMethodNotAllowed},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		rv.ServeHTTP(w, tt.req)
+		if w.Code != tt.want {
+			t.Errorf("%s request: status = %d; want %d", tt.name, w.Code, tt.want)
+		}
+	}
+
+	// Only the validly signed requests reach the bot, with their headers.
+	reqs := forwarded()
+	if len(reqs) != 2 {
+		t.Fatalf("forwarded %d requests; want the signed one and the bad JSON", len(reqs))
+	}
+	body, _ := ioutil.ReadAll(reqs[0].Body)
+	if string(body) != textPayload || reqs[0].Header.Get(kik.SignatureHeader) != signed.Header.Get(kik.SignatureHeader) {
+		t.Errorf("forwarded %q with signature %q; want the original request", body, reqs[0].Header.Get(kik.SignatureHeader))
+	}
+
+	// The inspector keeps the two most recent payloads, newest first.
+	recent := rv.inspector.recent()
+	if len(recent) != 2 || recent[0].DecodeError == "" || recent[1].ValidSignature {
+		t.Errorf("recent() = %+v; want the bad JSON, then the forged request", recent)
+	}
+}
+
+func TestReceiver_InsecureForwardsInvalidSignatures(t *testing.T) {
+	rv, forwarded, teardown := testReceiver(t, true)
+	defer teardown()
+
+	req := kiktest.WebhookRequest(rv.client, textPayload)
+	req.Header.Set(kik.SignatureHeader, "0000")
+	w := httptest.NewRecorder()
+	rv.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK || len(forwarded()) != 1 {
+		t.Errorf("status = %d and forwarded %d requests; want 200 and 1", w.Code, len(forwarded()))
+	}
+	if p := rv.inspector.recent()[0]; p.ValidSignature || !strings.HasPrefix(p.Forward, "200") {
+		t.Errorf("payload = %+v; want an invalid signature forwarded with 200 OK", p)
+	}
+}
+
+func TestInspector_ServesRecentPayloads(t *testing.T) {
+	i := newInspector(10)
+	i.add(payload{Username: "mybot", ValidSignature: true, Body: rawJSON([]byte(textPayload))})
+	i.add(payload{Username: "mybot", Body: rawJSON([]byte("<script>"))})
+
+	w := httptest.NewRecorder()
+	i.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/payloads", nil))
+	var listed []payload
+	if err := json.NewDecoder(w.Body).Decode(&listed); err != nil {
+		t.Fatalf("decoding /payloads: %v", err)
+	}
+	var quoted string
+	if len(listed) == 2 {
+		_ = json.Unmarshal(listed[0].Body, &quoted)
+	}
+	if len(listed) != 2 || quoted != "<script>" || !listed[1].ValidSignature {
+		t.Errorf("/payloads = %+v; want the quoted body, then the signed payload", listed)
+	}
+
+	w = httptest.NewRecorder()
+	i.serveHTML(w, httptest.NewRequest(http.MethodGet, "/", nil))
+	if html := w.Body.String(); !strings.Contains(html, "signature invalid") || strings.Contains(html, "<script>") {
+		t.Errorf("/ = %s; want both payloads with the body escaped", html)
+	}
+
+	w = httptest.NewRecorder()
+	i.serveHTML(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("GET /missing status = %d; want %d", w.Code, http.StatusNotFound)
+	}
+}
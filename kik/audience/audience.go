@@ -0,0 +1,238 @@
+// Package audience keeps track of the users who have chatted with a bot and broadcasts to segments of them.
+//
+// Wrap the bot's handler with Audience.Record to add every user who starts chatting or sends a message.
+// Users can opt out, and be tagged to build segments that a template message is broadcast to.
+package audience
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// NotFoundError is returned by a Store for users that never chatted with the bot.
+var NotFoundError = errors.New("user is not part of the audience")
+
+// Member is a user who has chatted with the bot.
+type Member struct {
+	Username  string    `json:"username"`
+	Tags      []string  `json:"tags,omitempty"`
+	OptedOut  bool      `json:"optedOut,omitempty"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// HasTag reports whether the member is tagged with tag, tags are case-insensitive.
+func (m Member) HasTag(tag string) bool {
+	for _, t := range m.Tags {
+		if strings.EqualFold(t, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// Store persists members. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns a member, or NotFoundError.
+	Get(ctx context.Context, username string) (*Member, error)
+	Put(ctx context.Context, m *Member) error
+	// Update applies fn to a member atomically, so concurrent updates of the same member aren't lost.
+	// Users that aren't members yet are added only when create is set, otherwise it returns NotFoundError.
+	Update(ctx context.Context, username string, create bool, fn func(m *Member)) error
+	List(ctx context.Context) ([]Member, error)
+}
+
+// Audience manages the members of a bot's audience.
+type Audience struct {
+	Store Store
+
+	// OptOutKeywords are text messages, compared case-insensitively, that opt the sender out.
+	OptOutKeywords []string
+	// OptOutReply is sent to users who opt out with a keyword. Their message is not passed on when set.
+	OptOutReply string
+}
+
+// New returns an Audience backed by store that opts users out when they send "stop".
+func New(store Store) *Audience {
+	return &Audience{Store: store, OptOutKeywords: []string{"stop"}}
+}
+
+// See records that a user chatted with the bot, adding them to the audience if needed.
+func (a *Audience) See(ctx context.Context, username string, at time.Time) error {
+	return a.update(ctx, username, true, func(m *Member) {
+		m.LastSeen = at
+		if m.FirstSeen.IsZero() {
+			m.FirstSeen = at
+		}
+	})
+}
+
+// OptOut excludes a user from every broadcast until they OptIn.
+func (a *Audience) OptOut(ctx context.Context, username string) error {
+	return a.update(ctx, username, false, func(m *Member) { m.OptedOut = true })
+}
+
+// OptIn undoes OptOut.
+func (a *Audience) OptIn(ctx context.Context, username string) error {
+	return a.update(ctx, username, false, func(m *Member) { m.OptedOut = false })
+}
+
+// Tag adds tags to a member.
+func (a *Audience) Tag(ctx context.Context, username string, tags ...string) error {
+	return a.update(ctx, username, false, func(m *Member) {
+		for _, t := range tags {
+			if !m.HasTag(t) {
+				m.Tags = append(m.Tags, t)
+			}
+		}
+	})
+}
+
+// Untag removes tags from a member.
+func (a *Audience) Untag(ctx context.Context, username string, tags ...string) error {
+	return a.update(ctx, username, false, func(m *Member) {
+		kept := m.Tags[:0]
+		for _, t := range m.Tags {
+			remove := false
+			for _, r := range tags {
+				remove = remove || strings.EqualFold(t, r)
+			}
+			if !remove {
+				kept = append(kept, t)
+			}
+		}
+		m.Tags = kept
+	})
+}
+
+// update applies fn to a member. Only create adds users that aren't part of the audience yet.
+func (a *Audience) update(ctx context.Context, username string, create bool, fn func(m *Member)) error {
+	return a.Store.Update(ctx, strings.ToLower(username), create, fn)
+}
+
+// Segment selects the members a broadcast is sent to. Opted out members are never selected.
+type Segment struct {
+	Tags      []string          // Members must have every one of these tags.
+	SeenSince time.Time         // Members must have chatted since, ignored when zero.
+	Filter    func(Member) bool // Optional extra condition.
+}
+
+// Matches reports whether m is part of the segment.
+func (s Segment) Matches(m Member) bool {
+	if m.OptedOut {
+		return false
+	}
+	for _, t := range s.Tags {
+		if !m.HasTag(t) {
+			return false
+		}
+	}
+	if !s.SeenSince.IsZero() && m.LastSeen.Before(s.SeenSince) {
+		return false
+	}
+	return s.Filter == nil || s.Filter(m)
+}
+
+// Members returns the usernames in a segment, sorted.
+func (a *Audience) Members(ctx context.Context, s Segment) ([]string, error) {
+	all, err := a.Store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, m := range all {
+		if s.Matches(m) {
+			names = append(names, m.Username)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Template builds the message sent to one member of a broadcast.
+type Template func(username string) kik.Message
+
+// TextTemplate sends the same text, and optionally keyboards, to every member.
+func TextTemplate(body string, keyboards ...kik.SuggestedResponseKeyboard) Template {
+	return func(username string) kik.Message {
+		return kik.TextMessage{
+			SendMessage: kik.SendMessage{To: username, Type: "text", Keyboards: keyboards},
+			Body:        body,
+		}
+	}
+}
+
+// Broadcast sends a message built from template to every member of the segment, in requests
+// of at most kik.MaxBroadcastMessages messages. It returns the number of members messaged,
+// stopping at the first failed request or once ctx is done.
+func (a *Audience) Broadcast(ctx context.Context, c *kik.Client, s Segment, template Template) (int, error) {
+	names, err := a.Members(ctx, s)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for start := 0; start < len(names); start += kik.MaxBroadcastMessages {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		end := start + kik.MaxBroadcastMessages
+		if end > len(names) {
+			end = len(names)
+		}
+		batch := make([]kik.Message, 0, end-start)
+		for _, name := range names[start:end] {
+			batch = append(batch, template(name))
+		}
+		if err := c.BroadcastMessage(batch); err != nil {
+			return sent, err
+		}
+		sent += len(batch)
+	}
+	return sent, nil
+}
+
+// Record returns a Handler that adds the sender of every message to the audience before calling next,
+// and opts users out who send one of the OptOutKeywords.
+func (a *Audience) Record(next kik.Handler) kik.Handler {
+	return kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		common := m.Common()
+		if common.From == "" {
+			return next.HandleMessage(ctx, c, m)
+		}
+
+		if err := a.See(ctx, common.From, time.Now()); err != nil {
+			return err
+		}
+
+		if t, ok := m.(*kik.TextMessageReceive); ok && a.isOptOut(t.Body) {
+			if err := a.OptOut(ctx, common.From); err != nil {
+				return err
+			}
+			if a.OptOutReply != "" {
+				return c.SendMessage([]kik.Message{kik.TextMessage{
+					SendMessage: kik.SendMessage{To: common.From, ChatId: common.ChatId, Type: "text"},
+					Body:        a.OptOutReply,
+				}})
+			}
+		}
+		return next.HandleMessage(ctx, c, m)
+	})
+}
+
+func (a *Audience) isOptOut(body string) bool {
+	body = strings.TrimSpace(body)
+	for _, k := range a.OptOutKeywords {
+		if strings.EqualFold(k, body) {
+			return true
+		}
+	}
+	return false
+}
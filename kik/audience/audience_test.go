@@ -0,0 +1,119 @@
+package audience_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/audience"
+	"github.com/4kelly/go-kik/kiktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecord_AddsSendersAndOptsOut(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	sent := kiktest.RecordMessages(mux)
+
+	a := audience.New(audience.NewMemoryStore())
+	a.OptOutReply = "You won't hear from us again."
+
+	handled := 0
+	h := a.Record(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		handled++
+		return nil
+	}))
+	ctx := context.Background()
+	messages := []kik.Receive{
+		&kik.StartChattingReceive{ReceiveMessage: kik.ReceiveMessage{From: "Alice", Type: "start-chatting"}},
+		&kik.TextMessageReceive{ReceiveMessage: kik.ReceiveMessage{From: "bob", Type: "text"}, Body: "hi"},
+		&kik.TextMessageReceive{ReceiveMessage: kik.ReceiveMessage{From: "carol", Type: "text"}, Body: " STOP "},
+	}
+	for _, m := range messages {
+		if err := h.HandleMessage(ctx, client, m); err != nil {
+			t.Fatalf("HandleMessage returned an error = %+v; expected no error", err)
+		}
+	}
+	_ = a.Tag(ctx, "bob", "weekly")
+
+	got, _ := a.Members(ctx, audience.Segment{})
+	if !cmp.Equal(got, []string{"alice", "bob"}) {
+		t.Errorf("Members() = %v; want [alice bob]", got)
+	}
+	got, _ = a.Members(ctx, audience.Segment{Tags: []string{"Weekly"}})
+	if !cmp.Equal(got, []string{"bob"}) {
+		t.Errorf("Members(weekly) = %v; want [bob]", got)
+	}
+	if handled != 2 || !cmp.Equal(sent.Bodies(), []string{a.OptOutReply}) {
+		t.Errorf("handled %d, sent %v; want 2 handled and the opt out reply", handled, sent.Bodies())
+	}
+}
+
+func TestBroadcast_ChunksRequests(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	sent := kiktest.RecordMessages(mux)
+
+	a := audience.New(audience.NewMemoryStore())
+	ctx := context.Background()
+	for i := 0; i < 230; i++ {
+		name := fmt.Sprintf("user%03d", i)
+		_ = a.See(ctx, name, time.Time{})
+	}
+	_ = a.OptOut(ctx, "user000")
+
+	n, err := a.Broadcast(ctx, client, audience.Segment{}, audience.TextTemplate("News!"))
+	if err != nil {
+		t.Fatalf("Broadcast returned an error = %+v; expected no error", err)
+	}
+
+	if n != 229 || len(sent.Messages()) != 229 || sent.Requests() != 3 {
+		t.Errorf("Broadcast() sent %d messages in %d requests, returned %d; want 229 in 3",
+			len(sent.Messages()), sent.Requests(), n)
+	}
+	if to := sent.Messages()[0]["to"]; to != "user001" {
+		t.Errorf("first message to = %v; want user001", to)
+	}
+}
+
+func TestAudience_ConcurrentSeeKeepsOptOutAndTags(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audience")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileStore, err := audience.OpenFileStore(filepath.Join(dir, "audience.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, store := range []audience.Store{audience.NewMemoryStore(), fileStore} {
+		a := audience.New(store)
+		ctx := context.Background()
+		_ = a.See(ctx, "alice", time.Now())
+
+		// Messages from alice in other chats keep updating her while she opts out and is tagged.
+		var wg sync.WaitGroup
+		for i := 0; i < 50; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_ = a.See(ctx, "alice", time.Now())
+			}()
+		}
+		_ = a.OptOut(ctx, "alice")
+		_ = a.Tag(ctx, "alice", "weekly")
+		wg.Wait()
+
+		m, err := store.Get(ctx, "alice")
+		if err != nil || !m.OptedOut || !m.HasTag("weekly") {
+			t.Errorf("%T: Get(alice) = %+v, %v; want her opted out and tagged", store, m, err)
+		}
+	}
+}
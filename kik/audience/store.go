@@ -0,0 +1,147 @@
+package audience
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// MemoryStore keeps members in memory.
+type MemoryStore struct {
+	mu      sync.Mutex
+	members map[string]Member
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{members: map[string]Member{}}
+}
+
+func (s *MemoryStore) Get(ctx context.Context, username string) (*Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	m, ok := s.members[username]
+	if !ok {
+		return nil, NotFoundError
+	}
+	m.Tags = append([]string(nil), m.Tags...)
+	return &m, nil
+}
+
+func (s *MemoryStore) Put(ctx context.Context, m *Member) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	copied := *m
+	copied.Tags = append([]string(nil), m.Tags...)
+	s.members[m.Username] = copied
+	return nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, username string, create bool, fn func(m *Member)) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(username, create, fn)
+}
+
+// update must be called with s.mu held.
+func (s *MemoryStore) update(username string, create bool, fn func(m *Member)) error {
+	m, ok := s.members[username]
+	if !ok && !create {
+		return NotFoundError
+	}
+	if !ok {
+		m = Member{Username: username}
+	}
+	m.Tags = append([]string(nil), m.Tags...)
+	fn(&m)
+	s.members[username] = m
+	return nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]Member, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := make([]Member, 0, len(s.members))
+	for _, m := range s.members {
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// FileStore keeps members in memory and writes them all to a JSON file on every change.
+// It suits audiences of up to a few thousand users on a single instance.
+type FileStore struct {
+	path   string
+	memory *MemoryStore
+}
+
+// OpenFileStore loads the members saved at path, a missing file is an empty audience.
+func OpenFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, memory: NewMemoryStore()}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var members []Member
+	if err := json.Unmarshal(b, &members); err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		s.memory.members[m.Username] = m
+	}
+	return s, nil
+}
+
+func (s *FileStore) Get(ctx context.Context, username string) (*Member, error) {
+	return s.memory.Get(ctx, username)
+}
+
+func (s *FileStore) Put(ctx context.Context, m *Member) error {
+	copied := *m
+	copied.Tags = append([]string(nil), m.Tags...)
+
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+	s.memory.members[m.Username] = copied
+	return s.save()
+}
+
+func (s *FileStore) Update(ctx context.Context, username string, create bool, fn func(m *Member)) error {
+	s.memory.mu.Lock()
+	defer s.memory.mu.Unlock()
+	if err := s.memory.update(username, create, fn); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// save writes every member to the file, it must be called with s.memory.mu held.
+func (s *FileStore) save() error {
+	members := make([]Member, 0, len(s.memory.members))
+	for _, m := range s.memory.members {
+		members = append(members, m)
+	}
+	b, err := json.MarshalIndent(members, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *FileStore) List(ctx context.Context) ([]Member, error) {
+	return s.memory.List(ctx)
+}
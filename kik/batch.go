@@ -0,0 +1,66 @@
+package kik
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchOptions controls how batch calls such as CreateCodes spread their requests.
+type BatchOptions struct {
+	Concurrency int           // Maximum requests in flight, defaults to 4.
+	Interval    time.Duration // Minimum time between starting two requests, zero means no throttling.
+}
+
+// forEach calls fn for every index in [0, n) with bounded parallelism and throttling.
+// It stops starting new calls once ctx is done and returns ctx.Err() in that case.
+func (o BatchOptions) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int)) error {
+	workers := o.Concurrency
+	if workers <= 0 {
+		workers = 4
+	}
+	if workers > n {
+		workers = n
+	}
+
+	var tick <-chan time.Time
+	if o.Interval > 0 {
+		ticker := time.NewTicker(o.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				fn(ctx, i)
+			}
+		}()
+	}
+
+	var err error
+feed:
+	for i := 0; i < n; i++ {
+		if tick != nil && i > 0 {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				err = ctx.Err()
+				break feed
+			}
+		}
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			err = ctx.Err()
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return err
+}
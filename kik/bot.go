@@ -0,0 +1,240 @@
+package kik
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Worker is a background task run alongside a Bot until it shuts down,
+// such as a scheduler.Scheduler or an outbox.Outbox.
+type Worker interface {
+	// Run works until ctx is done, then returns nil.
+	Run(ctx context.Context) error
+}
+
+// flusher is implemented by workers holding messages to send, such as an outbox.Outbox.
+// Bot.Shutdown flushes them one last time once they have stopped.
+type flusher interface {
+	Flush(ctx context.Context) (int, error)
+}
+
+// Bot runs a bot: it serves the Webhook, runs Workers in the background and,
+// when WebhookUrl or Configuration is set, configures the bot with Kik on start.
+//
+// Run it until its context is cancelled, or call Shutdown, to stop accepting messages,
+// wait for the ones being handled and stop the workers.
+type Bot struct {
+	Client  *Client
+	Webhook *Webhook
+	Workers []Worker
+
+	Addr string         // Address to listen on, defaults to ":8080".
+	Path string         // Path the webhook is served on, defaults to "/incoming".
+	Mux  *http.ServeMux // Serves the webhook at Path, add other endpoints to it before calling Run.
+
+	// WebhookUrl is the public URL of Path. When set, Run points the bot's webhook at it.
+	WebhookUrl string
+	// Configuration is applied with ApplyConfiguration on start, Webhook is replaced by WebhookUrl when set.
+	Configuration *Configuration
+
+	ShutdownTimeout time.Duration // How long Run waits for work to drain once its context is done, defaults to 30s.
+
+	// Server is the HTTP server Run serves Mux with, its Handler is replaced by Mux.
+	// It defaults to one with read and idle timeouts, so slow clients can't hold connections open.
+	// There is no write timeout by default, as a synchronous Webhook writes once its handler returns.
+	Server *http.Server
+
+	mu       sync.Mutex
+	closed   bool
+	server   *http.Server
+	stop     context.CancelFunc
+	workers  sync.WaitGroup
+	once     sync.Once
+	done     chan struct{} // Closed once Shutdown has finished.
+	shutdown error
+}
+
+// NewBot returns a Bot serving a Webhook that calls handler.
+func NewBot(client *Client, handler Handler) *Bot {
+	return &Bot{Client: client, Webhook: NewWebhook(client, handler), Mux: http.NewServeMux()}
+}
+
+// doneChan returns the channel closed once Shutdown has finished.
+func (b *Bot) doneChan() chan struct{} {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.done == nil {
+		b.done = make(chan struct{})
+	}
+	return b.done
+}
+
+// Configure applies Configuration and WebhookUrl, leaving the rest of the bot's configuration alone.
+func (b *Bot) Configure(ctx context.Context) ([]ConfigurationChange, error) {
+	desired := b.Configuration
+	if desired == nil {
+		if b.WebhookUrl == "" {
+			return nil, nil
+		}
+		current, err := b.Client.getConfiguration(ctx)
+		if err != nil {
+			return nil, err
+		}
+		desired = current
+	}
+
+	c := *desired
+	if b.WebhookUrl != "" {
+		c.Webhook = b.WebhookUrl
+	}
+	return b.Client.ApplyConfiguration(ctx, &c, false)
+}
+
+// Run configures the bot, starts the workers and serves the webhook until ctx is done or Shutdown is called.
+// It returns once everything has drained. The error is nil after a clean shutdown.
+func (b *Bot) Run(ctx context.Context) error {
+	changes, err := b.Configure(ctx)
+	if err != nil {
+		return fmt.Errorf("configuring %s: %w", b.Client.BotUsername, err)
+	}
+	for _, c := range changes {
+		log.Printf("configured %s: %s", b.Client.BotUsername, c)
+	}
+
+	addr := b.Addr
+	if addr == "" {
+		addr = ":8080"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return b.Serve(ctx, listener)
+}
+
+// Serve is Run on an existing listener, without configuring the bot.
+// It returns http.ErrServerClosed if the bot was already started or shut down.
+func (b *Bot) Serve(ctx context.Context, listener net.Listener) error {
+	done := b.doneChan()
+	workerCtx, stop := context.WithCancel(context.Background())
+	b.mu.Lock()
+	if b.closed || b.server != nil {
+		b.mu.Unlock()
+		stop()
+		return http.ErrServerClosed
+	}
+
+	path := b.Path
+	if path == "" {
+		path = "/incoming"
+	}
+	if b.Mux == nil {
+		b.Mux = http.NewServeMux()
+	}
+	b.Mux.Handle(path, b.Webhook)
+	server := b.Server
+	if server == nil {
+		server = &http.Server{
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+		}
+	}
+	server.Handler = b.Mux
+	b.server, b.stop = server, stop
+	b.mu.Unlock()
+
+	errs := make(chan error, len(b.Workers)+1)
+	for _, w := range b.Workers {
+		w := w
+		b.workers.Add(1)
+		go func() {
+			defer b.workers.Done()
+			if err := w.Run(workerCtx); err != nil {
+				errs <- fmt.Errorf("worker %T: %w", w, err)
+			}
+		}()
+	}
+	go func() {
+		if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+			errs <- err
+		}
+	}()
+
+	var runErr error
+	select {
+	case <-ctx.Done():
+	case <-done:
+	case runErr = <-errs:
+	}
+
+	timeout := b.ShutdownTimeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if err := b.Shutdown(shutdownCtx); runErr == nil {
+		runErr = err
+	}
+	return runErr
+}
+
+// Shutdown stops accepting webhook requests, waits for the messages being handled,
+// stops the workers and flushes the ones holding messages to send.
+// If ctx is done first, ctx.Err() is returned. Calling it again returns the first result.
+func (b *Bot) Shutdown(ctx context.Context) error {
+	done := b.doneChan()
+	b.once.Do(func() {
+		b.mu.Lock()
+		b.closed = true
+		server, stop := b.server, b.stop
+		b.mu.Unlock()
+
+		if server != nil {
+			b.shutdown = b.drain(ctx, server, stop)
+		}
+		close(done)
+	})
+	<-done
+	return b.shutdown
+}
+
+func (b *Bot) drain(ctx context.Context, server *http.Server, stop context.CancelFunc) error {
+	var firstErr error
+	if err := server.Shutdown(ctx); err != nil {
+		firstErr = err
+	}
+	if b.Webhook.Async != nil {
+		if err := b.Webhook.Async.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	stop()
+	stopped := make(chan struct{})
+	go func() {
+		b.workers.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for _, w := range b.Workers {
+		if f, ok := w.(flusher); ok {
+			if _, err := f.Flush(ctx); err != nil && firstErr == nil {
+				firstErr = fmt.Errorf("flushing %T: %w", w, err)
+			}
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,142 @@
+package kik_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+// fakeOutbox is a Worker that also flushes, like outbox.Outbox.
+type fakeOutbox struct {
+	running, flushed int32
+}
+
+func (o *fakeOutbox) Run(ctx context.Context) error {
+	atomic.StoreInt32(&o.running, 1)
+	<-ctx.Done()
+	atomic.StoreInt32(&o.running, 0)
+	return nil
+}
+
+func (o *fakeOutbox) Flush(ctx context.Context) (int, error) {
+	if atomic.LoadInt32(&o.running) == 1 {
+		return 0, fmt.Errorf("flushed while still running")
+	}
+	atomic.AddInt32(&o.flushed, 1)
+	return 0, nil
+}
+
+func TestBot_ServesUntilCancelledThenDrains(t *testing.T) {
+	client, _, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var handled int32
+	bot := kik.NewBot(client, kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		time.Sleep(50 * time.Millisecond)
+		atomic.AddInt32(&handled, 1)
+		return nil
+	}))
+	bot.Webhook.Async = kik.NewKeyedExecutor(10)
+	worker := &fakeOutbox{}
+	bot.Workers = []kik.Worker{worker}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	result := make(chan error, 1)
+	go func() { result <- bot.Serve(ctx, listener) }()
+
+	req := kiktest.WebhookRequest(client, textPayload)
+	req.RequestURI = ""
+	req.URL.Scheme, req.URL.Host = "http", listener.Addr().String()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("delivering a message: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("webhook status = %d; want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	// The message is still being handled when the bot is told to stop.
+	cancel()
+	if err := <-result; err != nil {
+		t.Fatalf("Serve() error = %v", err)
+	}
+	if atomic.LoadInt32(&handled) != 1 || atomic.LoadInt32(&worker.flushed) != 1 {
+		t.Errorf("handled %d messages, flushed %d times; want 1 and 1", handled, worker.flushed)
+	}
+	if err := bot.Serve(context.Background(), listener); err != http.ErrServerClosed {
+		t.Errorf("Serve() after shutdown error = %v; want %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestBot_ConfigureOnlyReplacesWebhook(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var written kik.Configuration
+	mux.HandleFunc(kik.ConfigtUrl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			body, _ := ioutil.ReadAll(r.Body)
+			_ = json.Unmarshal(body, &written)
+		}
+		fmt.Fprint(w, currentConfig)
+	})
+
+	bot := kik.NewBot(client, kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error { return nil }))
+	bot.WebhookUrl = "https://bots.example.com/incoming"
+	changes, err := bot.Configure(context.Background())
+	if err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+
+	if len(changes) != 1 || changes[0].Field != "webhook" {
+		t.Errorf("Configure() changes = %v; want only the webhook", changes)
+	}
+	if written.Webhook != bot.WebhookUrl || !written.Features.ReceiveReadReceipts {
+		t.Errorf("wrote %+v; want the webhook replaced and the features kept", written)
+	}
+}
+
+func TestBot_ServerTimesOutSlowClients(t *testing.T) {
+	client, _, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	bot := kik.NewBot(client, kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		return nil
+	}))
+	bot.Server = &http.Server{ReadHeaderTimeout: 50 * time.Millisecond}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = bot.Serve(ctx, listener) }()
+
+	// A client that never finishes its headers is disconnected.
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	fmt.Fprint(conn, "POST /incoming HTTP/1.1\r\nHost: bot\r\n")
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := ioutil.ReadAll(conn); err != nil {
+		t.Errorf("reading from a slow connection error = %v; want the server to close it", err)
+	}
+}
@@ -0,0 +1,309 @@
+package kik
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CodeColor is the color a Kik Code image is rendered in.
+// For the palette see the [docs](https://dev.kik.com/#/docs/messaging#kik-codes-api).
+type CodeColor int
+
+const (
+	CodeColorKikBlue CodeColor = iota
+	CodeColorTurquoise
+	CodeColorMint
+	CodeColorForest
+	CodeColorKikGreen
+	CodeColorSunshine
+	CodeColorOrangeCreamsicle
+	CodeColorBloodOrange
+	CodeColorCandyAppleRed
+	CodeColorSalmon
+	CodeColorCoral
+	CodeColorCranberry
+	CodeColorLavender
+	CodeColorRoyalPurple
+	CodeColorMarine
+	CodeColorSteel
+)
+
+var codeColorNames = []string{
+	"kik-blue",
+	"turquoise",
+	"mint",
+	"forest",
+	"kik-green",
+	"sunshine",
+	"orange-creamsicle",
+	"blood-orange",
+	"candy-apple-red",
+	"salmon",
+	"coral",
+	"cranberry",
+	"lavender",
+	"royal-purple",
+	"marine",
+	"steel",
+}
+
+// Valid reports whether c is part of Kik's palette.
+func (c CodeColor) Valid() bool {
+	return c >= 0 && int(c) < len(codeColorNames)
+}
+
+func (c CodeColor) String() string {
+	if !c.Valid() {
+		return fmt.Sprintf("CodeColor(%d)", int(c))
+	}
+	return codeColorNames[c]
+}
+
+// ParseCodeColor returns the color for a name as returned by CodeColor.String.
+func ParseCodeColor(name string) (CodeColor, error) {
+	for i, n := range codeColorNames {
+		if strings.EqualFold(n, name) {
+			return CodeColor(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown Kik Code color %q", name)
+}
+
+// CodeImageUrl returns the URL of the image for a Kik Code, relative to the BaseUrl of the Client.
+func CodeImageUrl(id string, color CodeColor) string {
+	return fmt.Sprintf("%s/%s?c=%d", CodeUrl, url.PathEscape(id), int(color))
+}
+
+// GetCodeImage returns the 1024x1024 PNG image for a Kik Code created with CreateCode.
+// Use png.Decode on the result if you need an image.Image.
+func (k *Client) GetCodeImage(ctx context.Context, id string, color CodeColor) ([]byte, error) {
+	if !color.Valid() {
+		return nil, fmt.Errorf("invalid Kik Code color %d", int(color))
+	}
+
+	req, err := k.newRequest(ctx, "GET", CodeImageUrl(id, color), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := k.doRaw(req, maxPictureBytes)
+	if err != nil {
+		return nil, err
+	}
+	if contentType := http.DetectContentType(img); contentType != "image/png" {
+		return nil, fmt.Errorf("expected a PNG Kik Code image, got %s", contentType)
+	}
+	return img, nil
+}
+
+// CodeCache remembers the Kik Code created for each ScanData, so identical payloads are only created once.
+type CodeCache interface {
+	GetCode(data string) (*Code, bool)
+	PutCode(data string, code *Code) error
+}
+
+// MemoryCodeCache is a CodeCache for the lifetime of the process.
+type MemoryCodeCache struct {
+	mu    sync.Mutex
+	codes map[string]Code
+}
+
+// NewMemoryCodeCache returns an empty MemoryCodeCache.
+func NewMemoryCodeCache() *MemoryCodeCache {
+	return &MemoryCodeCache{codes: map[string]Code{}}
+}
+
+func (c *MemoryCodeCache) GetCode(data string) (*Code, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	code, ok := c.codes[data]
+	if !ok {
+		return nil, false
+	}
+	return &code, true
+}
+
+func (c *MemoryCodeCache) PutCode(data string, code *Code) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.codes == nil {
+		c.codes = map[string]Code{}
+	}
+	c.codes[data] = *code
+	return nil
+}
+
+// FileCodeCache is a CodeCache kept in a JSON file mapping ScanData to Kik Code ids,
+// so reruns of a print campaign reuse the codes already created.
+type FileCodeCache struct {
+	path  string
+	cache *MemoryCodeCache
+}
+
+// OpenFileCodeCache loads the cache at path, a missing file is an empty cache.
+func OpenFileCodeCache(path string) (*FileCodeCache, error) {
+	c := &FileCodeCache{path: path, cache: NewMemoryCodeCache()}
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.cache.codes); err != nil {
+		return nil, fmt.Errorf("could not decode code cache %s: %w", path, err)
+	}
+	if c.cache.codes == nil {
+		// The file held null.
+		c.cache.codes = map[string]Code{}
+	}
+	return c, nil
+}
+
+func (c *FileCodeCache) GetCode(data string) (*Code, bool) {
+	return c.cache.GetCode(data)
+}
+
+// PutCode adds the code and rewrites the whole file.
+func (c *FileCodeCache) PutCode(data string, code *Code) error {
+	c.cache.mu.Lock()
+	defer c.cache.mu.Unlock()
+
+	c.cache.codes[data] = *code
+	b, err := json.MarshalIndent(c.cache.codes, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.path)
+}
+
+// CodeBatchOptions controls CreateCodes.
+type CodeBatchOptions struct {
+	BatchOptions
+
+	Cache          CodeCache // Optional, defaults to deduplicating within the batch only.
+	DownloadImages bool      // Also fetch the image of every code with GetCodeImage.
+	Color          CodeColor // Color of the downloaded images.
+}
+
+// CodeResult is the outcome of creating one code in CreateCodes.
+type CodeResult struct {
+	ScanData ScanData
+	Code     *Code
+	Cached   bool   // The code came from the cache or an identical payload earlier in the batch.
+	Image    []byte // PNG image, when CodeBatchOptions.DownloadImages is set.
+	Err      error
+}
+
+// CreateCodes creates a Kik Code for every ScanData concurrently, see BatchOptions.
+// Results are in the same order as data. Identical payloads are only created once.
+// The returned error is the first failure, every result carries its own Err.
+func (k *Client) CreateCodes(ctx context.Context, data []ScanData, opts *CodeBatchOptions) ([]CodeResult, error) {
+	if opts == nil {
+		opts = &CodeBatchOptions{}
+	}
+	cache := opts.Cache
+	if cache == nil {
+		cache = NewMemoryCodeCache()
+	}
+
+	// Group indexes by payload so each unique payload is a single job.
+	var unique []string
+	indexes := map[string][]int{}
+	for i, d := range data {
+		if _, ok := indexes[d.Data]; !ok {
+			unique = append(unique, d.Data)
+		}
+		indexes[d.Data] = append(indexes[d.Data], i)
+	}
+
+	results := make([]CodeResult, len(data))
+	err := opts.forEach(ctx, len(unique), func(ctx context.Context, u int) {
+		d := ScanData{Data: unique[u]}
+		r := CodeResult{ScanData: d}
+
+		if code, ok := cache.GetCode(d.Data); ok {
+			r.Code, r.Cached = code, true
+		} else if code, err := k.createCode(ctx, &d); err != nil {
+			r.Err = err
+		} else {
+			r.Code = code
+			r.Err = cache.PutCode(d.Data, code)
+		}
+
+		if r.Err == nil && opts.DownloadImages {
+			r.Image, r.Err = k.GetCodeImage(ctx, r.Code.Id, opts.Color)
+		}
+
+		for n, i := range indexes[d.Data] {
+			results[i] = r
+			results[i].Cached = r.Cached || n > 0
+		}
+	})
+
+	for i := range results {
+		if results[i].Code == nil && results[i].Err == nil {
+			results[i] = CodeResult{ScanData: data[i], Err: err}
+		}
+	}
+	if err != nil {
+		return results, err
+	}
+	for _, r := range results {
+		if r.Err != nil {
+			return results, r.Err
+		}
+	}
+	return results, nil
+}
+
+// ExportCodes writes the image of every result to <dir>/<code id>.png, and a manifest.json
+// listing the payload, code id, image file and error of every result.
+func ExportCodes(dir string, results []CodeResult) error {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	type entry struct {
+		Data  string `json:"data"`
+		Id    string `json:"id,omitempty"`
+		Image string `json:"image,omitempty"`
+		Error string `json:"error,omitempty"`
+	}
+	manifest := make([]entry, 0, len(results))
+	for _, r := range results {
+		e := entry{Data: r.ScanData.Data}
+		if r.Err != nil {
+			e.Error = r.Err.Error()
+		}
+		if r.Code != nil {
+			e.Id = r.Code.Id
+		}
+		if r.Code != nil && len(r.Image) > 0 {
+			e.Image = r.Code.Id + ".png"
+			if err := ioutil.WriteFile(filepath.Join(dir, e.Image), r.Image, 0o644); err != nil {
+				return err
+			}
+		}
+		manifest = append(manifest, e)
+	}
+
+	b, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "manifest.json"), b, 0o644)
+}
@@ -0,0 +1,172 @@
+package kik_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// pngBytes encodes a blank image of the given size.
+func pngBytes(t *testing.T, w, h int) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestGetCodeImage_HappyPath(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	want := pngBytes(t, 4, 4)
+	mux.HandleFunc(kik.CodeUrl+"/abc123", func(w http.ResponseWriter, r *http.Request) {
+		if got := r.URL.Query().Get("c"); got != "4" {
+			t.Errorf("color query = %q; want %q", got, "4")
+		}
+		w.Write(want)
+	})
+
+	got, err := client.GetCodeImage(context.Background(), "abc123", kik.CodeColorKikGreen)
+	if err != nil {
+		t.Errorf("GetCodeImage returned an error = %+v; expected no error", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("GetCodeImage() returned %d bytes; want %d", len(got), len(want))
+	}
+}
+
+func TestCodeColor_MatchesKikPalette(t *testing.T) {
+	tests := map[kik.CodeColor]string{
+		0:  "kik-blue",
+		4:  "kik-green",
+		7:  "blood-orange",
+		13: "royal-purple",
+		15: "steel",
+	}
+	for c, name := range tests {
+		if got := c.String(); got != name {
+			t.Errorf("CodeColor(%d).String() = %q; want %q", int(c), got, name)
+		}
+		if got, err := kik.ParseCodeColor(name); err != nil || got != c {
+			t.Errorf("ParseCodeColor(%q) = %d, %v; want %d", name, int(got), err, int(c))
+		}
+	}
+	if kik.CodeColor(16).Valid() {
+		t.Errorf("CodeColor(16).Valid() = true; the palette has 16 colors")
+	}
+}
+
+func TestGetCodeImage_RejectsNonImage(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	mux.HandleFunc(kik.CodeUrl+"/abc123", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"error": "nope"}`))
+	})
+
+	if _, err := client.GetCodeImage(context.Background(), "abc123", kik.CodeColorKikGreen); err == nil {
+		t.Errorf("GetCodeImage() of a JSON body returned no error")
+	}
+	if _, err := client.GetCodeImage(context.Background(), "abc123", kik.CodeColor(99)); err == nil {
+		t.Errorf("GetCodeImage() with an invalid color returned no error")
+	}
+}
+
+func TestCreateCodes_DedupesAndExports(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var mu sync.Mutex
+	created := map[string]int{}
+	mux.HandleFunc(kik.CodeUrl, func(w http.ResponseWriter, r *http.Request) {
+		var s kik.ScanData
+		_ = json.NewDecoder(r.Body).Decode(&s)
+		mu.Lock()
+		created[s.Data]++
+		mu.Unlock()
+		fmt.Fprintf(w, `{"id": "id-%s"}`, s.Data)
+	})
+	img := pngBytes(t, 2, 2)
+	mux.HandleFunc(kik.CodeUrl+"/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(img)
+	})
+
+	cache := kik.NewMemoryCodeCache()
+	_ = cache.PutCode("cached", &kik.Code{Id: "id-cached"})
+
+	data := []kik.ScanData{{Data: "a"}, {Data: "b"}, {Data: "a"}, {Data: "cached"}}
+	results, err := client.CreateCodes(context.Background(), data, &kik.CodeBatchOptions{
+		BatchOptions:   kik.BatchOptions{Concurrency: 2, Interval: time.Millisecond},
+		Cache:          cache,
+		DownloadImages: true,
+	})
+	if err != nil {
+		t.Fatalf("CreateCodes returned an error = %+v; expected no error", err)
+	}
+
+	wantCreated := map[string]int{"a": 1, "b": 1}
+	if !cmp.Equal(created, wantCreated) {
+		t.Errorf("created codes = %v; want %v", created, wantCreated)
+	}
+	for i, r := range results {
+		if r.Code.Id != "id-"+data[i].Data || !bytes.Equal(r.Image, img) {
+			t.Errorf("CreateCodes()[%d] = %v; want code id-%s with an image", i, r, data[i].Data)
+		}
+	}
+	if !results[2].Cached || !results[3].Cached || results[0].Cached {
+		t.Errorf("CreateCodes() cached flags = %v, %v, %v; want false, true, true",
+			results[0].Cached, results[2].Cached, results[3].Cached)
+	}
+
+	dir, err := ioutil.TempDir("", "codes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := kik.ExportCodes(dir, results); err != nil {
+		t.Fatalf("ExportCodes returned an error = %+v; expected no error", err)
+	}
+	for _, f := range []string{"manifest.json", "id-a.png", "id-b.png", "id-cached.png"} {
+		if _, err := os.Stat(filepath.Join(dir, f)); err != nil {
+			t.Errorf("ExportCodes() did not write %s: %v", f, err)
+		}
+	}
+}
+
+func TestOpenFileCodeCache_NullFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "codecache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "codes.json")
+	if err := ioutil.WriteFile(path, []byte("null"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	cache, err := kik.OpenFileCodeCache(path)
+	if err != nil {
+		t.Fatalf("OpenFileCodeCache() error = %v", err)
+	}
+	if err := cache.PutCode("data", &kik.Code{Id: "abc"}); err != nil {
+		t.Fatalf("PutCode() error = %v", err)
+	}
+	if code, ok := cache.GetCode("data"); !ok || code.Id != "abc" {
+		t.Errorf("GetCode() = %v, %v; want the code that was put", code, ok)
+	}
+}
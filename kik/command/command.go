@@ -0,0 +1,237 @@
+// Package command routes slash commands sent in text messages, such as "/subscribe weekly", to their handlers.
+//
+// A Registry is a kik.Handler. It parses the command line into Args, answers /help from the
+// registered commands and suggests the closest command when an unknown one is sent:
+//
+//	commands := command.New()
+//	commands.Register(&command.Command{
+//		Name:        "subscribe",
+//		Usage:       "<daily|weekly>",
+//		Description: "Get the newsletter",
+//		MinArgs:     1,
+//		Keyboard:    []string{"/subscribe daily", "/subscribe weekly"},
+//		Run: func(ctx context.Context, r *command.Request) error {
+//			return r.Reply("Subscribed " + r.Args.Arg(0))
+//		},
+//	})
+//	webhook := kik.NewWebhook(client, commands)
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// Command is a command the bot understands.
+type Command struct {
+	Name        string   // Without the prefix, compared case-insensitively.
+	Aliases     []string // Other names for the command, not shown by /help.
+	Usage       string   // The arguments, shown by /help, such as "<daily|weekly>".
+	Description string   // What the command does, shown by /help.
+
+	// MinArgs is the number of positional arguments required, the usage is sent when there are fewer.
+	MinArgs int
+	// Keyboard is shown as suggested responses with every Reply to the command.
+	Keyboard []string
+
+	Run func(ctx context.Context, r *Request) error
+}
+
+// Request is a command sent to the bot.
+type Request struct {
+	Client  *kik.Client
+	Message *kik.TextMessageReceive
+	Command *Command
+	Args    Args
+}
+
+// Reply sends text back to the chat the command came from, with the command's Keyboard.
+func (r *Request) Reply(text string) error {
+	return r.Client.SendMessage([]kik.Message{reply(r.Message, text, keyboardOf(r.Command))})
+}
+
+// Registry holds the commands of a bot and dispatches text messages to them. It implements kik.Handler.
+type Registry struct {
+	Prefix string // Starts every command, defaults to "/".
+
+	// Fallback handles every message that isn't a command. Those messages are ignored when nil.
+	Fallback kik.Handler
+	// HelpHeader is the first line of the /help reply, defaults to "Commands:".
+	HelpHeader string
+
+	commands []*Command
+	byName   map[string]*Command
+}
+
+// New returns an empty Registry using the "/" prefix.
+func New() *Registry {
+	return &Registry{Prefix: "/"}
+}
+
+// Register adds commands, failing if a name or alias is already taken. "help" is taken unless registered here.
+func (r *Registry) Register(commands ...*Command) error {
+	if r.byName == nil {
+		r.byName = map[string]*Command{}
+	}
+	for _, c := range commands {
+		if c.Name == "" || c.Run == nil {
+			return fmt.Errorf("command %q needs a name and a Run func", c.Name)
+		}
+		names := append([]string{c.Name}, c.Aliases...)
+		for _, name := range names {
+			if _, ok := r.byName[strings.ToLower(name)]; ok {
+				return fmt.Errorf("command %s%s is already registered", r.prefix(), name)
+			}
+		}
+		for _, name := range names {
+			r.byName[strings.ToLower(name)] = c
+		}
+		r.commands = append(r.commands, c)
+	}
+	return nil
+}
+
+// Lookup returns the command registered under name or one of its aliases.
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	c, ok := r.byName[strings.ToLower(name)]
+	return c, ok
+}
+
+// Help lists every command with its usage and description, in the order they were registered.
+func (r *Registry) Help() string {
+	header := r.HelpHeader
+	if header == "" {
+		header = "Commands:"
+	}
+
+	lines := []string{header}
+	for _, c := range r.commands {
+		line := r.prefix() + c.Name
+		if c.Usage != "" {
+			line += " " + c.Usage
+		}
+		if c.Description != "" {
+			line += " - " + c.Description
+		}
+		lines = append(lines, line)
+	}
+	if _, ok := r.Lookup("help"); !ok {
+		lines = append(lines, r.prefix()+"help - Show this message")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Suggest returns the names of the commands closest to an unknown name, closest first.
+// Only names within a third of the name's length, and at least one, edit are suggested.
+func (r *Registry) Suggest(name string) []string {
+	name = strings.ToLower(name)
+	limit := len([]rune(name)) / 3
+	if limit < 1 {
+		limit = 1
+	}
+
+	type candidate struct {
+		name string
+		d    int
+	}
+	var candidates []candidate
+	for _, c := range r.commands {
+		best := -1
+		for _, n := range append([]string{c.Name}, c.Aliases...) {
+			if d := distance(name, strings.ToLower(n)); d <= limit && (best < 0 || d < best) {
+				best = d
+			}
+		}
+		if best >= 0 {
+			candidates = append(candidates, candidate{c.Name, best})
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return candidates[i].d < candidates[j].d })
+
+	var names []string
+	for _, c := range candidates {
+		names = append(names, c.name)
+	}
+	return names
+}
+
+// HandleMessage runs the command in a text message. Messages that aren't commands go to Fallback.
+func (r *Registry) HandleMessage(ctx context.Context, c *kik.Client, m kik.Receive) error {
+	t, ok := m.(*kik.TextMessageReceive)
+	if !ok {
+		return r.fallback(ctx, c, m)
+	}
+	args, err := Parse(t.Body, r.prefix())
+	if errors.Is(err, NotCommandError) {
+		return r.fallback(ctx, c, m)
+	}
+	if err != nil {
+		return c.SendMessage([]kik.Message{reply(t, fmt.Sprintf("Sorry, I couldn't read that command: %v.", err), nil)})
+	}
+
+	cmd, ok := r.Lookup(args.Name)
+	switch {
+	case !ok && args.Name == "help":
+		return c.SendMessage([]kik.Message{reply(t, r.Help(), nil)})
+	case !ok:
+		return c.SendMessage([]kik.Message{r.unknown(t, args.Name)})
+	case len(args.Positional) < cmd.MinArgs:
+		return c.SendMessage([]kik.Message{reply(t, "Usage: "+r.prefix()+cmd.Name+" "+cmd.Usage, keyboardOf(cmd))})
+	}
+	return cmd.Run(ctx, &Request{Client: c, Message: t, Command: cmd, Args: args})
+}
+
+func (r *Registry) unknown(t *kik.TextMessageReceive, name string) kik.TextMessage {
+	suggestions := r.Suggest(name)
+	if len(suggestions) == 0 {
+		return reply(t, fmt.Sprintf("Unknown command %s%s. Send %shelp to see what I can do.", r.prefix(), name, r.prefix()), nil)
+	}
+
+	responses := make([]string, len(suggestions))
+	for i, s := range suggestions {
+		responses[i] = r.prefix() + s
+	}
+	return reply(t, fmt.Sprintf("Unknown command %s%s. Did you mean %s?", r.prefix(), name, strings.Join(responses, " or ")), responses)
+}
+
+func (r *Registry) fallback(ctx context.Context, c *kik.Client, m kik.Receive) error {
+	if r.Fallback == nil {
+		return nil
+	}
+	return r.Fallback.HandleMessage(ctx, c, m)
+}
+
+func (r *Registry) prefix() string {
+	if r.Prefix == "" {
+		return "/"
+	}
+	return r.Prefix
+}
+
+func keyboardOf(c *Command) []string {
+	if c == nil {
+		return nil
+	}
+	return c.Keyboard
+}
+
+// reply builds a text message back to the sender of m, with responses as a suggested response keyboard.
+func reply(m *kik.TextMessageReceive, text string, responses []string) kik.TextMessage {
+	msg := kik.TextMessage{
+		SendMessage: kik.SendMessage{To: m.From, ChatId: m.ChatId, Type: "text"},
+		Body:        text,
+	}
+	if len(responses) > 0 {
+		keyboard := kik.SuggestedResponseKeyboard{Type: "suggested"}
+		for _, r := range responses {
+			keyboard.Responses = append(keyboard.Responses, kik.KeyboardTextResponse{Type: "text", Body: r})
+		}
+		msg.Keyboards = []kik.SuggestedResponseKeyboard{keyboard}
+	}
+	return msg
+}
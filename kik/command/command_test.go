@@ -0,0 +1,144 @@
+package command_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/command"
+	"github.com/4kelly/go-kik/kiktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		text string
+		want command.Args
+	}{
+		{"/Subscribe weekly", command.Args{Name: "subscribe", Positional: []string{"weekly"}, Flags: map[string]string{}}},
+		{
+			`/remind --at=9am "water the plants" --quiet 'every day'`,
+			command.Args{Name: "remind", Positional: []string{"water the plants", "every day"}, Flags: map[string]string{"at": "9am", "quiet": "true"}},
+		},
+		{
+			`/note "say \"hi\"" "--not-a-flag" --title="two words"`,
+			command.Args{Name: "note", Positional: []string{`say "hi"`, "--not-a-flag"}, Flags: map[string]string{"title": "two words"}},
+		},
+		{
+			`/remind don't forget 'the milk'`,
+			command.Args{Name: "remind", Positional: []string{"don't", "forget", "the milk"}, Flags: map[string]string{}},
+		},
+	}
+	for _, tt := range tests {
+		got, err := command.Parse(tt.text, "/")
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.text, err)
+			continue
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Parse(%q) mismatch (-want +got):\n%s", tt.text, diff)
+		}
+	}
+
+	for _, text := range []string{"hello", "/", `/"quoted"`} {
+		if _, err := command.Parse(text, "/"); err != command.NotCommandError {
+			t.Errorf("Parse(%q) error = %v; want %v", text, err, command.NotCommandError)
+		}
+	}
+	if _, err := command.Parse(`/say "unfinished`, "/"); err == nil {
+		t.Errorf("Parse() with an unterminated quote error = nil; want an error")
+	}
+}
+
+func TestRegistry_HandleMessage(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	rec := kiktest.RecordMessages(mux)
+
+	var ran []string
+	registry := command.New()
+	err := registry.Register(
+		&command.Command{
+			Name:        "subscribe",
+			Aliases:     []string{"sub"},
+			Usage:       "<daily|weekly>",
+			Description: "Get the newsletter",
+			MinArgs:     1,
+			Keyboard:    []string{"/subscribe daily", "/subscribe weekly"},
+			Run: func(ctx context.Context, r *command.Request) error {
+				ran = append(ran, "subscribe "+r.Args.Arg(0))
+				return r.Reply("Subscribed " + r.Args.Arg(0))
+			},
+		},
+		&command.Command{
+			Name: "unsubscribe",
+			Run:  func(ctx context.Context, r *command.Request) error { return nil },
+		},
+	)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := registry.Register(&command.Command{Name: "SUB", Run: func(context.Context, *command.Request) error { return nil }}); err == nil {
+		t.Errorf("Register() of a taken alias error = nil; want an error")
+	}
+
+	var fellBack int
+	registry.Fallback = kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		fellBack++
+		return nil
+	})
+
+	for _, body := range []string{"/sub weekly", "/subscribe", "/subscrbe daily", "/xyz", "/help", "hello"} {
+		m := &kik.TextMessageReceive{ReceiveMessage: kik.ReceiveMessage{From: "alice", ChatId: "c1"}, Body: body}
+		if err := registry.HandleMessage(context.Background(), client, m); err != nil {
+			t.Fatalf("HandleMessage(%q) error = %v", body, err)
+		}
+	}
+
+	if len(ran) != 1 || ran[0] != "subscribe weekly" || fellBack != 1 {
+		t.Errorf("ran %v and fell back %d times; want only subscribe weekly and one fallback", ran, fellBack)
+	}
+
+	sent := rec.Messages()
+	if len(sent) != 5 {
+		t.Fatalf("sent %d messages; want 5", len(sent))
+	}
+	wantPrefixes := []string{
+		"Subscribed weekly",
+		"Usage: /subscribe <daily|weekly>",
+		"Unknown command /subscrbe. Did you mean /subscribe?",
+		"Unknown command /xyz. Send /help",
+		"Commands:\n/subscribe <daily|weekly> - Get the newsletter\n/unsubscribe\n/help - Show this message",
+	}
+	for i, want := range wantPrefixes {
+		if body, _ := sent[i]["body"].(string); !strings.HasPrefix(body, want) {
+			t.Errorf("message %d = %q; want it to start with %q", i+1, body, want)
+		}
+	}
+	if keyboards, _ := sent[0]["keyboards"].([]interface{}); len(keyboards) != 1 {
+		t.Errorf("reply keyboards = %v; want the command's keyboard", sent[0]["keyboards"])
+	}
+}
+
+func TestRegistry_Suggest(t *testing.T) {
+	registry := command.New()
+	noop := func(context.Context, *command.Request) error { return nil }
+	_ = registry.Register(
+		&command.Command{Name: "subscribe", Run: noop},
+		&command.Command{Name: "unsubscribe", Run: noop},
+		&command.Command{Name: "status", Aliases: []string{"stats"}, Run: noop},
+	)
+
+	tests := map[string][]string{
+		"subscribr":  {"subscribe", "unsubscribe"},
+		"unsubscrib": {"unsubscribe", "subscribe"},
+		"stat":       {"status"},
+		"weather":    nil,
+	}
+	for name, want := range tests {
+		if got := registry.Suggest(name); !cmp.Equal(got, want) {
+			t.Errorf("Suggest(%q) = %v; want %v", name, got, want)
+		}
+	}
+}
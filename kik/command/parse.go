@@ -0,0 +1,155 @@
+package command
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// NotCommandError is returned by Parse for text that doesn't start with the prefix.
+var NotCommandError = errors.New("not a command")
+
+// Args is a parsed command line such as `/remind --at=9am "water the plants" daily`.
+type Args struct {
+	Name       string            // The command name without the prefix, lower case.
+	Positional []string          // Arguments that aren't flags, in order.
+	Flags      map[string]string // --name=value flags. A bare --name is "true".
+}
+
+// Arg returns the i-th positional argument, or "" if there are fewer.
+func (a Args) Arg(i int) string {
+	if i < 0 || i >= len(a.Positional) {
+		return ""
+	}
+	return a.Positional[i]
+}
+
+// Flag returns the value of a flag and whether it was given.
+func (a Args) Flag(name string) (string, bool) {
+	v, ok := a.Flags[strings.ToLower(name)]
+	return v, ok
+}
+
+// Parse splits text starting with prefix into a command name, positional arguments and flags.
+// Arguments are separated by whitespace. Single or double quotes at the start of an argument, or of a
+// flag value, group words into one argument, and inside double quotes a backslash escapes the next character.
+// Quotes inside a word, like the apostrophe in "don't", are kept as they are. Quoted arguments are never flags.
+func Parse(text, prefix string) (Args, error) {
+	text = strings.TrimSpace(text)
+	if prefix == "" || !strings.HasPrefix(text, prefix) || len(text) == len(prefix) {
+		return Args{}, NotCommandError
+	}
+
+	tokens, err := tokenize(text[len(prefix):])
+	if err != nil {
+		return Args{}, err
+	}
+	if len(tokens) == 0 || tokens[0].quoted {
+		return Args{}, NotCommandError
+	}
+
+	args := Args{Name: strings.ToLower(tokens[0].text), Flags: map[string]string{}}
+	for _, t := range tokens[1:] {
+		if t.quoted || !strings.HasPrefix(t.text, "--") || len(t.text) == 2 {
+			args.Positional = append(args.Positional, t.text)
+			continue
+		}
+		name, value := strings.TrimPrefix(t.text, "--"), "true"
+		if i := strings.Index(name, "="); i >= 0 {
+			name, value = name[:i], name[i+1:]
+		}
+		args.Flags[strings.ToLower(name)] = value
+	}
+	return args, nil
+}
+
+type token struct {
+	text   string
+	quoted bool // The token started with a quote.
+}
+
+func tokenize(s string) ([]token, error) {
+	var tokens []token
+	var current strings.Builder
+	inToken, quoted := false, false
+	var quote rune
+	escaped := false
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			current.WriteRune(r)
+			escaped = false
+		case quote != 0:
+			switch {
+			case r == quote:
+				quote = 0
+			case r == '\\' && quote == '"':
+				escaped = true
+			default:
+				current.WriteRune(r)
+			}
+		case (r == '"' || r == '\'') && (!inToken || opensFlagValue(current.String())):
+			if !inToken {
+				quoted = true
+			}
+			inToken, quote = true, r
+		case unicode.IsSpace(r):
+			if inToken {
+				tokens = append(tokens, token{text: current.String(), quoted: quoted})
+				current.Reset()
+				inToken, quoted = false, false
+			}
+		default:
+			current.WriteRune(r)
+			inToken = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inToken {
+		tokens = append(tokens, token{text: current.String(), quoted: quoted})
+	}
+	return tokens, nil
+}
+
+// opensFlagValue reports whether a quote after token starts a flag value, as in --title="two words".
+// Anywhere else inside a word a quote is taken literally, so apostrophes don't need escaping.
+func opensFlagValue(token string) bool {
+	return strings.HasPrefix(token, "--") && strings.HasSuffix(token, "=")
+}
+
+// distance is the Levenshtein distance between a and b: the number of single character
+// insertions, deletions and substitutions that turn one into the other.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = smallest(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func smallest(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
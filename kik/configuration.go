@@ -0,0 +1,129 @@
+package kik
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ConfigurationChange is a single field that differs between two configurations.
+// Old and New are the JSON encoding of the field, Old is empty when the field was not set.
+type ConfigurationChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+func (c ConfigurationChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
+
+// DiffConfiguration returns the field level changes needed to turn current into desired.
+// A nil current configuration is treated as an empty one.
+// Keyboards are compared by their JSON encoding, so a keyboard read back from the API
+// (decoded into maps) is equal to the typed keyboard it was set from.
+func DiffConfiguration(current, desired *Configuration) ([]ConfigurationChange, error) {
+	if current == nil {
+		current = &Configuration{}
+	}
+	if desired == nil {
+		desired = &Configuration{}
+	}
+
+	fields := []struct {
+		name     string
+		cur, des interface{}
+	}{
+		{"webhook", current.Webhook, desired.Webhook},
+		{"features.manuallySendReadReceipts", current.ManuallySendReadReceipts, desired.ManuallySendReadReceipts},
+		{"features.receiveReadReceipts", current.ReceiveReadReceipts, desired.ReceiveReadReceipts},
+		{"features.receiveDeliveryReceipts", current.ReceiveDeliveryReceipts, desired.ReceiveDeliveryReceipts},
+		{"features.receiveIsTyping", current.ReceiveIsTyping, desired.ReceiveIsTyping},
+		{"staticKeyboard", current.StaticKeyboard, desired.StaticKeyboard},
+	}
+
+	var changes []ConfigurationChange
+	for _, f := range fields {
+		cur, err := normalizedJSON(f.cur)
+		if err != nil {
+			return nil, err
+		}
+		des, err := normalizedJSON(f.des)
+		if err != nil {
+			return nil, err
+		}
+		if cur != des {
+			changes = append(changes, ConfigurationChange{Field: f.name, Old: cur, New: des})
+		}
+	}
+	return changes, nil
+}
+
+// ApplyConfiguration fetches the current configuration and sets desired only if something changed.
+// The returned changes are what was (or with dryRun, would have been) written.
+func (k *Client) ApplyConfiguration(ctx context.Context, desired *Configuration, dryRun bool) ([]ConfigurationChange, error) {
+	current, err := k.getConfiguration(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not get current configuration: %w", err)
+	}
+
+	changes, err := DiffConfiguration(current, desired)
+	if err != nil {
+		return nil, err
+	}
+	if len(changes) == 0 || dryRun {
+		return changes, nil
+	}
+
+	if err := k.setConfiguration(ctx, desired); err != nil {
+		return nil, fmt.Errorf("could not set configuration: %w", err)
+	}
+	return changes, nil
+}
+
+// ReadConfiguration decodes a JSON configuration, as stored in a file checked into git.
+func ReadConfiguration(r io.Reader) (*Configuration, error) {
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+
+	var c Configuration
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("could not decode configuration: %w", err)
+	}
+	return &c, nil
+}
+
+// ReadConfigurationFile is ReadConfiguration for a file path.
+func ReadConfigurationFile(path string) (*Configuration, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadConfiguration(f)
+}
+
+// normalizedJSON encodes v with sorted object keys so values of different Go types compare equal.
+// nil values encode to an empty string.
+func normalizedJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	if bytes.Equal(b, []byte("null")) {
+		return "", nil
+	}
+
+	var generic interface{}
+	if err := json.Unmarshal(b, &generic); err != nil {
+		return "", err
+	}
+	b, err = json.Marshal(generic)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
@@ -0,0 +1,91 @@
+package kik_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+// currentConfig is what the mocked Kik API returns for GET /v1/config.
+const currentConfig = `{
+	"webhook": "https://example.com/incoming",
+	"features": {"receiveReadReceipts": true},
+	"staticKeyboard": {"type": "suggested", "responses": [{"type": "text", "body": "Hi"}]}
+}`
+
+func TestDiffConfiguration_KeyboardFromApiEqualsTypedKeyboard(t *testing.T) {
+	current, err := kik.ReadConfiguration(strings.NewReader(currentConfig))
+	if err != nil {
+		t.Fatalf("ReadConfiguration returned an error = %+v; expected no error", err)
+	}
+	desired := &kik.Configuration{
+		Webhook: "https://example.com/v2/incoming",
+		Features: kik.Features{
+			ReceiveReadReceipts: true,
+			ReceiveIsTyping:     true,
+		},
+		StaticKeyboard: &kik.SuggestedResponseKeyboard{
+			Type:      "suggested",
+			Responses: []interface{}{kik.KeyboardTextResponse{Type: "text", Body: "Hi"}},
+		},
+	}
+
+	got, err := kik.DiffConfiguration(current, desired)
+	if err != nil {
+		t.Fatalf("DiffConfiguration returned an error = %+v; expected no error", err)
+	}
+
+	want := []kik.ConfigurationChange{
+		{Field: "webhook", Old: `"https://example.com/incoming"`, New: `"https://example.com/v2/incoming"`},
+		{Field: "features.receiveIsTyping", Old: "false", New: "true"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("DiffConfiguration() = %v; want %v", got, want)
+	}
+}
+
+func TestApplyConfiguration_OnlyWritesChanges(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	writes := 0
+	mux.HandleFunc(kik.ConfigtUrl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			writes++
+		}
+		fmt.Fprint(w, currentConfig)
+	})
+
+	unchanged, _ := kik.ReadConfiguration(strings.NewReader(currentConfig))
+	changed := *unchanged
+	changed.Webhook = "https://example.com/v2/incoming"
+
+	tests := []struct {
+		name        string
+		desired     *kik.Configuration
+		dryRun      bool
+		wantChanges int
+		wantWrites  int
+	}{
+		{"unchanged", unchanged, false, 0, 0},
+		{"dry run", &changed, true, 1, 0},
+		{"changed", &changed, false, 1, 1},
+	}
+	for _, tt := range tests {
+		writes = 0
+		changes, err := client.ApplyConfiguration(context.Background(), tt.desired, tt.dryRun)
+		if err != nil {
+			t.Errorf("%s: ApplyConfiguration returned an error = %+v; expected no error", tt.name, err)
+		}
+		if len(changes) != tt.wantChanges || writes != tt.wantWrites {
+			t.Errorf("%s: ApplyConfiguration() = %d changes, %d writes; want %d changes, %d writes",
+				tt.name, len(changes), writes, tt.wantChanges, tt.wantWrites)
+		}
+	}
+}
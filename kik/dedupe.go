@@ -0,0 +1,126 @@
+package kik
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SeenStore remembers the ids of handled messages. Implementations must be safe for concurrent use.
+// Implement it on top of a shared cache such as Redis when several instances serve the same bot.
+type SeenStore interface {
+	// MarkSeen records id and reports whether it was already recorded within window.
+	MarkSeen(ctx context.Context, id string, window time.Duration) (bool, error)
+	// Forget removes id, so the message is handled again when Kik redelivers it.
+	Forget(ctx context.Context, id string) error
+}
+
+// Deduplicator skips messages Kik delivered more than once, keyed by ReceiveMessage.Id.
+// Set it on a Webhook to drop duplicates before the Handler runs.
+type Deduplicator struct {
+	Store  SeenStore
+	Window time.Duration // How long an id is remembered, defaults to 1h.
+
+	duplicates uint64
+}
+
+// NewDeduplicator returns a Deduplicator remembering up to size ids in memory for window.
+func NewDeduplicator(window time.Duration, size int) *Deduplicator {
+	return &Deduplicator{Store: NewMemorySeenStore(size), Window: window}
+}
+
+// Duplicate reports whether m was already handled, counting it if so.
+// Messages without an Id are never duplicates.
+func (d *Deduplicator) Duplicate(ctx context.Context, m Receive) (bool, error) {
+	id := m.Common().Id
+	if id == "" {
+		return false, nil
+	}
+
+	window := d.Window
+	if window <= 0 {
+		window = time.Hour
+	}
+	seen, err := d.Store.MarkSeen(ctx, id, window)
+	if err != nil {
+		return false, err
+	}
+	if seen {
+		atomic.AddUint64(&d.duplicates, 1)
+	}
+	return seen, nil
+}
+
+// Forget lets m be handled again, used when handling it failed.
+func (d *Deduplicator) Forget(ctx context.Context, m Receive) error {
+	if id := m.Common().Id; id != "" {
+		return d.Store.Forget(ctx, id)
+	}
+	return nil
+}
+
+// Duplicates returns how many duplicate messages were skipped.
+func (d *Deduplicator) Duplicates() uint64 {
+	return atomic.LoadUint64(&d.duplicates)
+}
+
+// MemorySeenStore is a SeenStore holding the most recently seen ids in memory.
+// Once full the least recently seen id is forgotten.
+type MemorySeenStore struct {
+	size int
+
+	mu  sync.Mutex
+	ids map[string]*list.Element
+	lru *list.List // Front is the most recently seen *seenId.
+}
+
+type seenId struct {
+	id string
+	at time.Time
+}
+
+// NewMemorySeenStore returns a MemorySeenStore remembering up to size ids, 10000 if size is not positive.
+func NewMemorySeenStore(size int) *MemorySeenStore {
+	if size <= 0 {
+		size = 10000
+	}
+	return &MemorySeenStore{size: size, ids: map[string]*list.Element{}, lru: list.New()}
+}
+
+func (s *MemorySeenStore) MarkSeen(ctx context.Context, id string, window time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if el, ok := s.ids[id]; ok {
+		seen := el.Value.(*seenId)
+		if now.Sub(seen.at) < window {
+			s.lru.MoveToFront(el)
+			return true, nil
+		}
+		seen.at = now
+		s.lru.MoveToFront(el)
+		return false, nil
+	}
+
+	s.ids[id] = s.lru.PushFront(&seenId{id: id, at: now})
+	for s.lru.Len() > s.size {
+		oldest := s.lru.Back()
+		s.lru.Remove(oldest)
+		delete(s.ids, oldest.Value.(*seenId).id)
+	}
+	return false, nil
+}
+
+func (s *MemorySeenStore) Forget(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.ids[id]; ok {
+		s.lru.Remove(el)
+		delete(s.ids, id)
+	}
+	return nil
+}
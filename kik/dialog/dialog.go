@@ -0,0 +1,280 @@
+// Package dialog declares conversation flows as a set of states, instead of a switch statement per bot.
+//
+// Each State has a Prompt the bot sends when the flow enters it, the inputs it accepts and
+// where to go next. A Flow is a kik.Handler, its progress is kept in the conversation's session
+// so it must be wrapped with session.Wrap:
+//
+//	flow := &dialog.Flow{
+//		Name:  "signup",
+//		Start: "name",
+//		States: []dialog.State{
+//			{Name: "name", Prompt: dialog.Prompt{Text: "What's your name?"}, Next: dialog.Goto("plan")},
+//			{Name: "plan", Prompt: dialog.Prompt{Text: "Which plan?", Responses: []string{"Free", "Pro"}}},
+//		},
+//		OnComplete: saveSignup,
+//	}
+//	handler := session.Wrap(store, session.ByChatAndUser, flow)
+package dialog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/session"
+)
+
+// NoSessionError is returned when a Flow handles a message outside of session.Wrap.
+var NoSessionError = errors.New("dialog flows must be wrapped with session.Wrap")
+
+// Answers holds the accepted input of every state visited so far, keyed by state name.
+type Answers map[string]string
+
+// Prompt is what the bot sends when a flow enters a state.
+type Prompt struct {
+	Text      string
+	Responses []string // Shown as a suggested response keyboard, if any.
+	Hidden    bool     // Hide the keyboard until the user opens it.
+}
+
+// Message builds the TextMessage for the prompt, addressed to the sender of m.
+func (p Prompt) Message(m kik.Receive) kik.TextMessage {
+	c := m.Common()
+	msg := kik.TextMessage{
+		SendMessage: kik.SendMessage{To: c.From, ChatId: c.ChatId, Type: "text"},
+		Body:        p.Text,
+	}
+	if len(p.Responses) > 0 {
+		keyboard := kik.SuggestedResponseKeyboard{Type: "suggested", Hidden: p.Hidden}
+		for _, r := range p.Responses {
+			keyboard.Responses = append(keyboard.Responses, kik.KeyboardTextResponse{Type: "text", Body: r})
+		}
+		msg.Keyboards = []kik.SuggestedResponseKeyboard{keyboard}
+	}
+	return msg
+}
+
+// State is a single step of a Flow.
+type State struct {
+	Name   string
+	Prompt Prompt
+
+	// Accept lists the expected inputs, compared case-insensitively. Anything else is rejected.
+	// When empty, any input is accepted unless Validate says otherwise.
+	Accept []string
+	// Validate optionally checks the input, its error is sent to the user before prompting again.
+	Validate func(input string) error
+	// Next returns the name of the following state, or "" to finish the flow. A nil Next finishes the flow.
+	Next func(input string, answers Answers) string
+}
+
+// Goto returns a Next func that always moves to the named state.
+func Goto(name string) func(string, Answers) string {
+	return func(string, Answers) string { return name }
+}
+
+// Branch returns a Next func that picks the state by input, compared case-insensitively,
+// falling back to fallback for inputs not in the map.
+func Branch(transitions map[string]string, fallback string) func(string, Answers) string {
+	return func(input string, _ Answers) string {
+		for in, next := range transitions {
+			if strings.EqualFold(in, input) {
+				return next
+			}
+		}
+		return fallback
+	}
+}
+
+// Flow is a conversation made of States. It implements kik.Handler.
+type Flow struct {
+	Name   string // Must be unique among the flows sharing a session.
+	Start  string // Name of the first state.
+	States []State
+
+	// Input extracts the user's input from a message, returning false for messages the flow ignores.
+	// Defaults to TextInput.
+	Input func(m kik.Receive) (string, bool)
+	// OnComplete is called with every answer once the flow finishes.
+	OnComplete func(ctx context.Context, c *kik.Client, m kik.Receive, answers Answers) error
+	// Invalid is sent when an input is not accepted, defaults to "Sorry, I didn't get that."
+	Invalid string
+}
+
+// TextInput uses the body of text messages as input, or the metadata of a suggested response when set.
+func TextInput(m kik.Receive) (string, bool) {
+	t, ok := m.(*kik.TextMessageReceive)
+	if !ok {
+		return "", false
+	}
+	if t.Metadata != "" {
+		return t.Metadata, true
+	}
+	return strings.TrimSpace(t.Body), true
+}
+
+// Check verifies the flow is well formed: state names are unique and Start exists.
+// Transitions are funcs, so states they name are only checked when they are reached.
+func (f *Flow) Check() error {
+	seen := map[string]bool{}
+	for _, s := range f.States {
+		if s.Name == "" {
+			return fmt.Errorf("flow %s has a state without a name", f.Name)
+		}
+		if seen[s.Name] {
+			return fmt.Errorf("flow %s has more than one state named %s", f.Name, s.Name)
+		}
+		seen[s.Name] = true
+	}
+	if !seen[f.Start] {
+		return fmt.Errorf("flow %s starts in unknown state %s", f.Name, f.Start)
+	}
+	return nil
+}
+
+// Active reports whether the conversation in ctx is part way through the flow.
+func (f *Flow) Active(ctx context.Context) bool {
+	s := session.FromContext(ctx)
+	return s != nil && s.Get(f.stateKey()) != ""
+}
+
+// Begin starts the flow from the Start state, abandoning any progress made so far.
+func (f *Flow) Begin(ctx context.Context, c *kik.Client, m kik.Receive) error {
+	s := session.FromContext(ctx)
+	if s == nil {
+		return NoSessionError
+	}
+	f.reset(s)
+	return f.enter(s, c, m, f.Start)
+}
+
+// Cancel abandons the flow without calling OnComplete.
+func (f *Flow) Cancel(ctx context.Context) {
+	if s := session.FromContext(ctx); s != nil {
+		f.reset(s)
+	}
+}
+
+// HandleMessage begins the flow if it isn't active, otherwise it feeds the message to the current state.
+func (f *Flow) HandleMessage(ctx context.Context, c *kik.Client, m kik.Receive) error {
+	s := session.FromContext(ctx)
+	if s == nil {
+		return NoSessionError
+	}
+	if !f.Active(ctx) {
+		return f.Begin(ctx, c, m)
+	}
+
+	state, ok := f.state(s.Get(f.stateKey()))
+	if !ok {
+		return fmt.Errorf("flow %s is in unknown state %s", f.Name, s.Get(f.stateKey()))
+	}
+
+	inputFunc := f.Input
+	if inputFunc == nil {
+		inputFunc = TextInput
+	}
+	input, ok := inputFunc(m)
+	if !ok {
+		return nil
+	}
+
+	if ok, reason := state.check(input); !ok {
+		if reason == "" {
+			reason = f.Invalid
+		}
+		if reason == "" {
+			reason = "Sorry, I didn't get that."
+		}
+		retry := state.Prompt.Message(m)
+		retry.Body = reason + "\n" + retry.Body
+		return c.SendMessage([]kik.Message{retry})
+	}
+
+	s.Set(f.answerKey(state.Name), input)
+	answers := f.answers(s)
+
+	next := ""
+	if state.Next != nil {
+		next = state.Next(input, answers)
+	}
+	if next != "" {
+		return f.enter(s, c, m, next)
+	}
+
+	f.reset(s)
+	if f.OnComplete != nil {
+		return f.OnComplete(ctx, c, m, answers)
+	}
+	return nil
+}
+
+// check reports whether input is accepted, and if not the reason given by Validate.
+func (s State) check(input string) (bool, string) {
+	if len(s.Accept) > 0 {
+		accepted := false
+		for _, a := range s.Accept {
+			if strings.EqualFold(a, input) {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			return false, ""
+		}
+	}
+	if s.Validate != nil {
+		if err := s.Validate(input); err != nil {
+			return false, err.Error()
+		}
+	}
+	return true, ""
+}
+
+func (f *Flow) enter(s *session.Session, c *kik.Client, m kik.Receive, name string) error {
+	state, ok := f.state(name)
+	if !ok {
+		return fmt.Errorf("flow %s has no state named %s", f.Name, name)
+	}
+	s.Set(f.stateKey(), name)
+	return c.SendMessage([]kik.Message{state.Prompt.Message(m)})
+}
+
+func (f *Flow) state(name string) (State, bool) {
+	for _, s := range f.States {
+		if s.Name == name {
+			return s, true
+		}
+	}
+	return State{}, false
+}
+
+func (f *Flow) answers(s *session.Session) Answers {
+	answers := Answers{}
+	prefix := f.answerKey("")
+	for k, v := range s.Values {
+		if strings.HasPrefix(k, prefix) {
+			answers[strings.TrimPrefix(k, prefix)] = v
+		}
+	}
+	return answers
+}
+
+func (f *Flow) reset(s *session.Session) {
+	prefix := "dialog/" + f.Name + "/"
+	for k := range s.Values {
+		if strings.HasPrefix(k, prefix) {
+			s.Delete(k)
+		}
+	}
+}
+
+func (f *Flow) stateKey() string {
+	return "dialog/" + f.Name + "/state"
+}
+
+func (f *Flow) answerKey(state string) string {
+	return "dialog/" + f.Name + "/answer/" + state
+}
@@ -0,0 +1,89 @@
+package dialog_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/dialog"
+	"github.com/4kelly/go-kik/kik/session"
+	"github.com/4kelly/go-kik/kiktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func text(body string) kik.Receive {
+	return &kik.TextMessageReceive{
+		ReceiveMessage: kik.ReceiveMessage{ChatId: "c1", From: "alice", Type: "text"},
+		Body:           body,
+	}
+}
+
+func TestFlow_HappyPath(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	sent := kiktest.RecordMessages(mux)
+
+	var got dialog.Answers
+	flow := &dialog.Flow{
+		Name:  "signup",
+		Start: "name",
+		States: []dialog.State{
+			{
+				Name:   "name",
+				Prompt: dialog.Prompt{Text: "What's your name?"},
+				Validate: func(input string) error {
+					if len(input) < 2 {
+						return errors.New("That's a bit short.")
+					}
+					return nil
+				},
+				Next: dialog.Goto("plan"),
+			},
+			{
+				Name:   "plan",
+				Prompt: dialog.Prompt{Text: "Which plan?", Responses: []string{"Free", "Pro"}},
+				Accept: []string{"Free", "Pro"},
+			},
+		},
+		OnComplete: func(ctx context.Context, c *kik.Client, m kik.Receive, answers dialog.Answers) error {
+			got = answers
+			return nil
+		},
+	}
+	if err := flow.Check(); err != nil {
+		t.Fatalf("Check() returned an error = %+v; expected no error", err)
+	}
+
+	h := session.Wrap(session.NewMemoryStore(time.Hour), session.ByChatAndUser, flow)
+	for _, input := range []string{"hello", "A", "Ann", "Enterprise", "pro"} {
+		if err := h.HandleMessage(context.Background(), client, text(input)); err != nil {
+			t.Fatalf("HandleMessage(%s) returned an error = %+v; expected no error", input, err)
+		}
+	}
+
+	wantBodies := []string{
+		"What's your name?",
+		"That's a bit short.\nWhat's your name?",
+		"Which plan?",
+		"Sorry, I didn't get that.\nWhich plan?",
+	}
+	if !cmp.Equal(sent.Bodies(), wantBodies) {
+		t.Errorf("sent %v; want %v", sent.Bodies(), wantBodies)
+	}
+
+	want := dialog.Answers{"name": "Ann", "plan": "pro"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("OnComplete answers = %v; want %v", got, want)
+	}
+}
+
+func TestFlow_RequiresSession(t *testing.T) {
+	flow := &dialog.Flow{Name: "f", Start: "a", States: []dialog.State{{Name: "a"}}}
+
+	err := flow.HandleMessage(context.Background(), nil, text("hi"))
+	if err != dialog.NoSessionError {
+		t.Errorf("HandleMessage() outside of session.Wrap = %v; want %v", err, dialog.NoSessionError)
+	}
+}
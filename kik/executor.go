@@ -0,0 +1,182 @@
+package kik
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// Executor runs tasks in the background. Tasks with the same key run one at a time, in the order
+// they were submitted. WorkerPool and KeyedExecutor are both Executors.
+type Executor interface {
+	// Submit queues a task, or returns an error if it can't be queued.
+	Submit(ctx context.Context, key string, task func(ctx context.Context)) error
+	// Shutdown stops accepting tasks and waits for the queued ones, until ctx is done.
+	Shutdown(ctx context.Context) error
+}
+
+// OverflowPolicy decides what KeyedExecutor.Submit does when a key's queue is full.
+type OverflowPolicy int
+
+const (
+	OverflowReject     OverflowPolicy = iota // Return QueueFullError.
+	OverflowBlock                            // Wait for room until ctx is done.
+	OverflowDropOldest                       // Drop the oldest queued task of the key to make room.
+)
+
+// KeyedExecutor runs tasks concurrently across keys and strictly sequentially within a key.
+// Unlike a WorkerPool, every key has a queue of its own, so a slow chat never holds up another.
+type KeyedExecutor struct {
+	QueueDepth     int            // Maximum queued tasks per key, defaults to 100.
+	Overflow       OverflowPolicy // What to do when a key's queue is full.
+	MaxConcurrency int            // Maximum keys running at once, unlimited when 0.
+
+	mu      sync.Mutex
+	keys    map[string]*keyQueue
+	closed  bool
+	slots   chan struct{}
+	running sync.WaitGroup
+	ctx     context.Context
+	cancel  context.CancelFunc
+	dropped uint64
+}
+
+// keyQueue holds the tasks waiting for a key. space is closed, and replaced, whenever a task leaves the queue.
+type keyQueue struct {
+	tasks []func(context.Context)
+	space chan struct{}
+}
+
+// NewKeyedExecutor returns a KeyedExecutor with queues of depth tasks that rejects tasks on overflow.
+func NewKeyedExecutor(depth int) *KeyedExecutor {
+	return &KeyedExecutor{QueueDepth: depth}
+}
+
+func (e *KeyedExecutor) init() {
+	if e.keys == nil {
+		e.keys = map[string]*keyQueue{}
+		e.ctx, e.cancel = context.WithCancel(context.Background())
+		if e.MaxConcurrency > 0 {
+			e.slots = make(chan struct{}, e.MaxConcurrency)
+		}
+	}
+}
+
+// Submit queues a task for key, handling a full queue according to Overflow.
+// The task's context is cancelled if Shutdown gives up waiting for it.
+func (e *KeyedExecutor) Submit(ctx context.Context, key string, task func(ctx context.Context)) error {
+	depth := e.QueueDepth
+	if depth <= 0 {
+		depth = 100
+	}
+
+	e.mu.Lock()
+	for {
+		if e.closed {
+			e.mu.Unlock()
+			return PoolClosedError
+		}
+		e.init()
+
+		q, ok := e.keys[key]
+		if !ok {
+			q = &keyQueue{space: make(chan struct{})}
+			e.keys[key] = q
+			e.running.Add(1)
+			go e.drain(key, q)
+		}
+		if len(q.tasks) < depth {
+			q.tasks = append(q.tasks, task)
+			e.mu.Unlock()
+			return nil
+		}
+
+		switch e.Overflow {
+		case OverflowDropOldest:
+			q.tasks = append(q.tasks[1:], task)
+			atomic.AddUint64(&e.dropped, 1)
+			e.mu.Unlock()
+			return nil
+		case OverflowBlock:
+			space := q.space
+			e.mu.Unlock()
+			select {
+			case <-space:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			e.mu.Lock()
+		default:
+			e.mu.Unlock()
+			return QueueFullError
+		}
+	}
+}
+
+// drain runs the tasks of a key until its queue is empty, then forgets the key.
+func (e *KeyedExecutor) drain(key string, q *keyQueue) {
+	defer e.running.Done()
+	for {
+		e.mu.Lock()
+		if len(q.tasks) == 0 {
+			delete(e.keys, key)
+			close(q.space)
+			e.mu.Unlock()
+			return
+		}
+		task := q.tasks[0]
+		q.tasks[0] = nil
+		q.tasks = q.tasks[1:]
+		close(q.space)
+		q.space = make(chan struct{})
+		e.mu.Unlock()
+
+		if e.slots != nil {
+			e.slots <- struct{}{}
+		}
+		task(e.ctx)
+		if e.slots != nil {
+			<-e.slots
+		}
+	}
+}
+
+// Len returns the number of queued tasks, not counting the ones running.
+func (e *KeyedExecutor) Len() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	n := 0
+	for _, q := range e.keys {
+		n += len(q.tasks)
+	}
+	return n
+}
+
+// Dropped returns how many tasks OverflowDropOldest has dropped.
+func (e *KeyedExecutor) Dropped() uint64 {
+	return atomic.LoadUint64(&e.dropped)
+}
+
+// Shutdown stops accepting tasks and waits for the queued ones to finish.
+// If ctx is done first, running tasks have their context cancelled and ctx.Err() is returned.
+func (e *KeyedExecutor) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	e.closed = true
+	e.init()
+	e.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		e.running.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		e.cancel()
+		return nil
+	case <-ctx.Done():
+		e.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,91 @@
+package kik_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+func TestKeyedExecutor_SerialWithinKeyConcurrentAcross(t *testing.T) {
+	e := kik.NewKeyedExecutor(10)
+	ctx := context.Background()
+
+	// c1 is stuck until c2 has run, which only works if c2 doesn't wait behind c1.
+	c2done := make(chan struct{})
+	var mu sync.Mutex
+	var order []int
+	for i := 0; i < 5; i++ {
+		i := i
+		err := e.Submit(ctx, "c1", func(context.Context) {
+			if i == 0 {
+				<-c2done
+			}
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+		if err != nil {
+			t.Fatalf("Submit(c1) error = %v", err)
+		}
+	}
+	if err := e.Submit(ctx, "c2", func(context.Context) { close(c2done) }); err != nil {
+		t.Fatalf("Submit(c2) error = %v", err)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+	for i, got := range order {
+		if got != i {
+			t.Fatalf("c1 ran in order %v; want 0 to 4", order)
+		}
+	}
+}
+
+func TestKeyedExecutor_Overflow(t *testing.T) {
+	tests := []struct {
+		policy  kik.OverflowPolicy
+		wantErr error
+		wantRan []string
+	}{
+		{kik.OverflowReject, kik.QueueFullError, []string{"running", "queued"}},
+		{kik.OverflowDropOldest, nil, []string{"running", "overflow"}},
+		{kik.OverflowBlock, context.DeadlineExceeded, []string{"running", "queued"}},
+	}
+	for _, tt := range tests {
+		e := kik.NewKeyedExecutor(1)
+		e.Overflow = tt.policy
+
+		started, release := make(chan struct{}), make(chan struct{})
+		var ran []string
+		record := func(name string) func(context.Context) {
+			return func(context.Context) { ran = append(ran, name) }
+		}
+		_ = e.Submit(context.Background(), "c1", func(ctx context.Context) {
+			close(started)
+			<-release
+			record("running")(ctx)
+		})
+		<-started
+		_ = e.Submit(context.Background(), "c1", record("queued"))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		err := e.Submit(ctx, "c1", record("overflow"))
+		cancel()
+		if !errors.Is(err, tt.wantErr) {
+			t.Errorf("policy %d: Submit() error = %v; want %v", tt.policy, err, tt.wantErr)
+		}
+
+		close(release)
+		_ = e.Shutdown(context.Background())
+		if len(ran) != len(tt.wantRan) || ran[0] != tt.wantRan[0] || ran[1] != tt.wantRan[1] {
+			t.Errorf("policy %d: ran %v; want %v", tt.policy, ran, tt.wantRan)
+		}
+	}
+}
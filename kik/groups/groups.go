@@ -0,0 +1,195 @@
+// Package groups helps bots behave in group chats: it tracks who is in each chat,
+// detects when the bot was mentioned and keeps bots quiet unless they were.
+package groups
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// Chat types as sent in ReceiveMessage.ChatType.
+const (
+	ChatTypeDirect  = "direct"
+	ChatTypePrivate = "private" // A private group.
+	ChatTypePublic  = "public"  // A public group.
+)
+
+// IsGroup reports whether m was sent in a group rather than a direct chat.
+func IsGroup(m kik.Receive) bool {
+	t := m.Common().ChatType
+	return t == ChatTypePrivate || t == ChatTypePublic
+}
+
+// Mentioned reports whether the bot was explicitly mentioned in m, either through the
+// Mention field or an @bot in the body of a text message. Usernames are case-insensitive.
+func Mentioned(m kik.Receive, bot string) bool {
+	if strings.EqualFold(m.Common().Mention, bot) {
+		return true
+	}
+	if t, ok := m.(*kik.TextMessageReceive); ok {
+		return patternsFor(bot).mention.MatchString(t.Body)
+	}
+	return false
+}
+
+// StripMention removes every @bot mention from body, along with any comma or colon and the
+// spaces right after it, then trims the edges. The rest of the body, line breaks and indentation
+// included, is left alone, and bodies without a mention are returned unchanged.
+func StripMention(body, bot string) string {
+	strip := patternsFor(bot).strip
+	if !strip.MatchString(body) {
+		return body
+	}
+	return strings.TrimSpace(strip.ReplaceAllString(body, "${1}"))
+}
+
+// mentionPatterns match an @bot mention, and the mention with what StripMention removes after it.
+type mentionPatterns struct {
+	mention *regexp.Regexp
+	strip   *regexp.Regexp
+}
+
+// patterns caches the mentionPatterns of every bot name seen, lower-cased. A process only serves
+// a handful of bots, so it isn't bounded.
+var patterns sync.Map
+
+// patternsFor returns the mentionPatterns of bot, compiling them the first time only.
+func patternsFor(bot string) *mentionPatterns {
+	key := strings.ToLower(bot)
+	if p, ok := patterns.Load(key); ok {
+		return p.(*mentionPatterns)
+	}
+	mention := `(?i)(^|\s)@` + regexp.QuoteMeta(key) + `\b`
+	p, _ := patterns.LoadOrStore(key, &mentionPatterns{
+		mention: regexp.MustCompile(mention),
+		strip:   regexp.MustCompile(mention + `[,:]?[ \t]*`),
+	})
+	return p.(*mentionPatterns)
+}
+
+// Change is the difference between two observations of a chat's participants.
+type Change struct {
+	ChatId string
+	Joined []string
+	Left   []string
+}
+
+// Empty reports whether nobody joined or left.
+func (c Change) Empty() bool {
+	return len(c.Joined) == 0 && len(c.Left) == 0
+}
+
+// Tracker remembers the participants of every chat it observes, so joins and leaves can be detected.
+// The first observation of a chat is the baseline and reports no change.
+type Tracker struct {
+	mu    sync.Mutex
+	chats map[string]map[string]bool
+}
+
+// NewTracker returns a Tracker that has observed no chats.
+func NewTracker() *Tracker {
+	return &Tracker{chats: map[string]map[string]bool{}}
+}
+
+// Observe records the participants of the chat m was sent in, and returns who joined or left since the last message.
+func (t *Tracker) Observe(m kik.Receive) Change {
+	c := m.Common()
+	change := Change{ChatId: c.ChatId}
+	if c.ChatId == "" || c.Participants == nil {
+		return change
+	}
+
+	now := map[string]bool{}
+	for _, p := range c.Participants {
+		now[strings.ToLower(p)] = true
+	}
+
+	t.mu.Lock()
+	before, seen := t.chats[c.ChatId]
+	t.chats[c.ChatId] = now
+	t.mu.Unlock()
+
+	if !seen {
+		return change
+	}
+	for p := range now {
+		if !before[p] {
+			change.Joined = append(change.Joined, p)
+		}
+	}
+	for p := range before {
+		if !now[p] {
+			change.Left = append(change.Left, p)
+		}
+	}
+	sort.Strings(change.Joined)
+	sort.Strings(change.Left)
+	return change
+}
+
+// Participants returns the last observed participants of a chat, lower-cased and sorted.
+func (t *Tracker) Participants(chatId string) []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []string
+	for p := range t.chats[chatId] {
+		out = append(out, p)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Forget drops everything known about a chat, for example once the bot leaves it.
+func (t *Tracker) Forget(chatId string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.chats, chatId)
+}
+
+// Track returns a Handler that observes every message with t, calling onChange when
+// participants joined or left, before passing the message on to next.
+func Track(t *Tracker, onChange func(ctx context.Context, c *kik.Client, change Change) error, next kik.Handler) kik.Handler {
+	return kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		if change := t.Observe(m); !change.Empty() && onChange != nil {
+			if err := onChange(ctx, c, change); err != nil {
+				return err
+			}
+		}
+		return next.HandleMessage(ctx, c, m)
+	})
+}
+
+// Options control which group messages Route passes on. Direct messages are always passed on.
+type Options struct {
+	IgnoreGroups   bool // Drop every group message.
+	RequireMention bool // Drop group messages that don't mention the bot.
+	KeepMention    bool // Don't strip the @bot mention from the body of text messages.
+}
+
+// Route returns a Handler that only passes on the group messages allowed by opts.
+// The bot is the BotUsername of the Client the message was received for.
+func Route(opts Options, next kik.Handler) kik.Handler {
+	return kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		if !IsGroup(m) {
+			return next.HandleMessage(ctx, c, m)
+		}
+		if opts.IgnoreGroups {
+			return nil
+		}
+		if opts.RequireMention && !Mentioned(m, c.BotUsername) {
+			return nil
+		}
+		if t, ok := m.(*kik.TextMessageReceive); ok && !opts.KeepMention {
+			stripped := *t
+			stripped.Body = StripMention(t.Body, c.BotUsername)
+			m = &stripped
+		}
+		return next.HandleMessage(ctx, c, m)
+	})
+}
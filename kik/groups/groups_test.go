@@ -0,0 +1,88 @@
+package groups_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/groups"
+	"github.com/google/go-cmp/cmp"
+)
+
+func groupText(body string, participants ...string) *kik.TextMessageReceive {
+	return &kik.TextMessageReceive{
+		ReceiveMessage: kik.ReceiveMessage{
+			ChatId:       "g1",
+			Type:         "text",
+			ChatType:     groups.ChatTypePublic,
+			Participants: participants,
+		},
+		Body: body,
+	}
+}
+
+func TestTracker_DetectsJoinsAndLeaves(t *testing.T) {
+	tracker := groups.NewTracker()
+
+	first := tracker.Observe(groupText("hi", "alice", "bob"))
+	second := tracker.Observe(groupText("hi", "Alice", "carol"))
+
+	if !first.Empty() {
+		t.Errorf("first Observe() = %v; want no change", first)
+	}
+	want := groups.Change{ChatId: "g1", Joined: []string{"carol"}, Left: []string{"bob"}}
+	if !cmp.Equal(second, want) {
+		t.Errorf("Observe() = %v; want %v", second, want)
+	}
+	if got := tracker.Participants("g1"); !cmp.Equal(got, []string{"alice", "carol"}) {
+		t.Errorf("Participants() = %v; want [alice carol]", got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		body      string
+		mentioned bool
+		stripped  string
+	}{
+		{"@mybot what's up", true, "what's up"},
+		{"hey @MyBot, play a song", true, "hey play a song"},
+		{"email me at bob@mybot.com", false, "email me at bob@mybot.com"},
+		{"@mybotfan hi", false, "@mybotfan hi"},
+		{"line one\n\n  indented code\tx", false, "line one\n\n  indented code\tx"},
+		{"@mybot: run\n  step one\n  step two", true, "run\n  step one\n  step two"},
+	}
+	// The patterns are compiled once per bot, whatever the case of its name.
+	for _, bot := range []string{"mybot", "MyBot"} {
+		for _, tt := range tests {
+			if got := groups.Mentioned(groupText(tt.body), bot); got != tt.mentioned {
+				t.Errorf("Mentioned(%q, %s) = %v; want %v", tt.body, bot, got, tt.mentioned)
+			}
+			if got := groups.StripMention(tt.body, bot); got != tt.stripped {
+				t.Errorf("StripMention(%q, %s) = %q; want %q", tt.body, bot, got, tt.stripped)
+			}
+		}
+	}
+}
+
+func TestRoute_OnlyWhenMentioned(t *testing.T) {
+	client, _ := kik.NewKikClient("https://api.kik.com/", "mybot", "key", nil)
+
+	var got []string
+	h := groups.Route(groups.Options{RequireMention: true}, kik.HandlerFunc(
+		func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+			got = append(got, m.(*kik.TextMessageReceive).Body)
+			return nil
+		}))
+
+	direct := groupText("no mention needed")
+	direct.ChatType = groups.ChatTypeDirect
+	for _, m := range []kik.Receive{groupText("chatter"), groupText("@mybot help"), direct} {
+		_ = h.HandleMessage(context.Background(), client, m)
+	}
+
+	want := []string{"help", "no mention needed"}
+	if !cmp.Equal(got, want) {
+		t.Errorf("handled %v; want %v", got, want)
+	}
+}
@@ -0,0 +1,141 @@
+package kik
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Health serves liveness and readiness probes for a bot, on any path ending in /healthz or /readyz.
+//
+// /healthz only reports that the process is serving requests. /readyz also checks that the API key works
+// and that the bot's webhook points at WebhookUrl, with a GetConfiguration cached for CacheFor,
+// and that Backlog has fewer than MaxBacklog messages waiting.
+type Health struct {
+	Client     *Client
+	WebhookUrl string // The URL the bot is served on, not checked when empty.
+
+	Backlog    interface{ Len() int } // Optional, such as the Webhook's WorkerPool or KeyedExecutor.
+	MaxBacklog int                    // Defaults to 1000.
+
+	CacheFor time.Duration // How long a configuration check is trusted, defaults to 30s.
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	checkErr  error
+	checking  *configCheck // The fetch in flight, shared by every probe that arrives meanwhile.
+}
+
+// configCheck is a configuration fetch, done is closed once err is set.
+type configCheck struct {
+	done      chan struct{}
+	err       error
+	cancelled bool // The fetch was cut short by the context of the probe that made it.
+}
+
+// NewHealth returns a Health checking that the bot's webhook is webhookUrl.
+func NewHealth(client *Client, webhookUrl string) *Health {
+	return &Health{Client: client, WebhookUrl: webhookUrl}
+}
+
+func (h *Health) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/healthz"):
+		fmt.Fprintln(w, "ok")
+	case strings.HasSuffix(r.URL.Path, "/readyz"):
+		if err := h.Ready(r.Context()); err != nil {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintln(w, "ok")
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// Ready returns why the bot can't take messages, or nil if it can.
+func (h *Health) Ready(ctx context.Context) error {
+	if h.Backlog != nil {
+		max := h.MaxBacklog
+		if max <= 0 {
+			max = 1000
+		}
+		if n := h.Backlog.Len(); n >= max {
+			return fmt.Errorf("%d messages waiting, the limit is %d", n, max)
+		}
+	}
+	return h.checkConfiguration(ctx)
+}
+
+// checkConfiguration fetches the configuration at most once per CacheFor, whether it passed or not.
+// Probes arriving while a fetch is in flight wait for it rather than making their own, so a burst of
+// probes makes one API call. Failures caused by ctx, such as a probe timing out, aren't cached or shared,
+// so they don't outlast the probe: its waiters try again instead.
+func (h *Health) checkConfiguration(ctx context.Context) error {
+	cacheFor := h.CacheFor
+	if cacheFor <= 0 {
+		cacheFor = 30 * time.Second
+	}
+
+	for {
+		h.mu.Lock()
+		if !h.checkedAt.IsZero() && time.Since(h.checkedAt) < cacheFor {
+			defer h.mu.Unlock()
+			return h.checkErr
+		}
+		if c := h.checking; c != nil {
+			h.mu.Unlock()
+			select {
+			case <-c.done:
+			case <-ctx.Done():
+				return fmt.Errorf("getting the configuration: %w", ctx.Err())
+			}
+			if !c.cancelled {
+				return c.err
+			}
+			continue
+		}
+		c := &configCheck{done: make(chan struct{})}
+		h.checking = c
+		h.mu.Unlock()
+
+		c.err = h.fetchConfiguration(ctx)
+		c.cancelled = ctx.Err() != nil
+
+		h.mu.Lock()
+		h.checking = nil
+		if !c.cancelled {
+			h.checkedAt, h.checkErr = time.Now(), c.err
+		}
+		h.mu.Unlock()
+		close(c.done)
+		return c.err
+	}
+}
+
+func (h *Health) fetchConfiguration(ctx context.Context) error {
+	config, err := h.Client.getConfiguration(ctx)
+	if err != nil {
+		return fmt.Errorf("getting the configuration: %w", err)
+	}
+	if h.WebhookUrl != "" && config.Webhook != h.WebhookUrl {
+		return fmt.Errorf("webhook points at %q, not %q", config.Webhook, h.WebhookUrl)
+	}
+	return nil
+}
+
+// Health returns a Health for the bot, checking its WebhookUrl and the backlog of its Webhook.
+// Serve it next to the webhook with
+//
+//	bot.Mux.Handle("/healthz", health)
+//	bot.Mux.Handle("/readyz", health)
+func (b *Bot) Health() *Health {
+	h := NewHealth(b.Client, b.WebhookUrl)
+	if backlog, ok := b.Webhook.Async.(interface{ Len() int }); ok {
+		h.Backlog = backlog
+	}
+	return h
+}
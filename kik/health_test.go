@@ -0,0 +1,127 @@
+package kik_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+type fixedBacklog int
+
+func (b fixedBacklog) Len() int { return int(b) }
+
+func TestHealth_Readiness(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	calls := 0
+	mux.HandleFunc(kik.ConfigtUrl, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, currentConfig)
+	})
+
+	probe := func(h *kik.Health, path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	ready := kik.NewHealth(client, "https://example.com/incoming")
+	moved := kik.NewHealth(client, "https://bots.example.com/incoming")
+	backedUp := kik.NewHealth(client, "")
+	backedUp.Backlog, backedUp.MaxBacklog = fixedBacklog(10), 10
+
+	tests := []struct {
+		health *kik.Health
+		path   string
+		want   int
+	}{
+		{ready, "/healthz", http.StatusOK},
+		{ready, "/readyz", http.StatusOK},
+		{ready, "/readyz", http.StatusOK},
+		{moved, "/healthz", http.StatusOK},
+		{moved, "/readyz", http.StatusServiceUnavailable},
+		{backedUp, "/readyz", http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		if got := probe(tt.health, tt.path); got != tt.want {
+			t.Errorf("GET %s for webhook %q: status = %d; want %d", tt.path, tt.health.WebhookUrl, got, tt.want)
+		}
+	}
+
+	// The second readiness probe of ready is cached, the backed up one never reaches the API.
+	if calls != 2 {
+		t.Errorf("GET %s called %d times; want 2", kik.ConfigtUrl, calls)
+	}
+}
+
+func TestHealth_ApiKeyRejected(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	mux.HandleFunc(kik.ConfigtUrl, func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	})
+
+	if err := kik.NewHealth(client, "").Ready(context.Background()); err == nil {
+		t.Errorf("Ready() error = nil; want an error when the API key is rejected")
+	}
+}
+
+func TestHealth_ProbeTimeoutIsNotCached(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var fast int32
+	mux.HandleFunc(kik.ConfigtUrl, func(w http.ResponseWriter, r *http.Request) {
+		if atomic.LoadInt32(&fast) == 0 {
+			<-r.Context().Done()
+			return
+		}
+		fmt.Fprint(w, currentConfig)
+	})
+
+	h := kik.NewHealth(client, "https://example.com/incoming")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := h.Ready(ctx); err == nil {
+		t.Fatalf("Ready() error = nil; want the probe to time out")
+	}
+
+	atomic.StoreInt32(&fast, 1)
+	if err := h.Ready(context.Background()); err != nil {
+		t.Errorf("Ready() after a timed out probe error = %v; want nil", err)
+	}
+}
+
+func TestHealth_ConcurrentProbesShareOneCheck(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var calls int32
+	mux.HandleFunc(kik.ConfigtUrl, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(50 * time.Millisecond)
+		fmt.Fprint(w, currentConfig)
+	})
+
+	h := kik.NewHealth(client, "https://example.com/incoming")
+	errs := make(chan error, 20)
+	for i := 0; i < cap(errs); i++ {
+		go func() { errs <- h.Ready(context.Background()) }()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Errorf("Ready() error = %v; want nil", err)
+		}
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Errorf("GET %s called %d times; want 1", kik.ConfigtUrl, n)
+	}
+}
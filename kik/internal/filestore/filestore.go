@@ -0,0 +1,69 @@
+// Package filestore holds the helpers shared by the file backed stores of kik/scheduler and kik/outbox,
+// which keep every record in its own JSON file.
+package filestore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// WriteJSON replaces the file at path with v encoded as JSON. It writes a temporary file and renames it
+// into place, so a crash never leaves half a record behind.
+func WriteJSON(path string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(path+".tmp", b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(path+".tmp", path)
+}
+
+// ReadAll returns the contents of every .json file in dir.
+func ReadAll(dir string) ([][]byte, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	encoded := make([][]byte, 0, len(files))
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+		encoded = append(encoded, b)
+	}
+	return encoded, nil
+}
+
+// Record is a decoded record with the times Due filters and sorts it by.
+type Record struct {
+	Value interface{}
+	DueAt time.Time // The record is due once now reaches DueAt.
+	Order time.Time // Due records are returned earliest Order first.
+}
+
+// Due decodes every record with decode and returns the ones due at now, cut to limit when it is positive.
+// A zero now returns every record.
+func Due(encoded [][]byte, now time.Time, limit int, decode func(b []byte) (Record, error)) ([]Record, error) {
+	var records []Record
+	for _, b := range encoded {
+		r, err := decode(b)
+		if err != nil {
+			return nil, err
+		}
+		if now.IsZero() || !r.DueAt.After(now) {
+			records = append(records, r)
+		}
+	}
+	sort.SliceStable(records, func(a, b int) bool { return records[a].Order.Before(records[b].Order) })
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+	return records, nil
+}
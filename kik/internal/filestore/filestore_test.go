@@ -0,0 +1,73 @@
+package filestore_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik/internal/filestore"
+	"github.com/google/go-cmp/cmp"
+)
+
+type record struct {
+	Name string
+	At   time.Time
+}
+
+func TestWriteJSONAndDue(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filestore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	for _, r := range []record{
+		{"later", now.Add(time.Minute)},
+		{"first", now.Add(-2 * time.Hour)},
+		{"second", now.Add(-time.Hour)},
+	} {
+		if err := filestore.WriteJSON(filepath.Join(dir, r.Name+".json"), r); err != nil {
+			t.Fatalf("WriteJSON() error = %v", err)
+		}
+	}
+	if tmp, _ := filepath.Glob(filepath.Join(dir, "*.tmp")); len(tmp) != 0 {
+		t.Errorf("WriteJSON() left %v behind", tmp)
+	}
+
+	encoded, err := filestore.ReadAll(dir)
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	decode := func(b []byte) (filestore.Record, error) {
+		var r record
+		err := json.Unmarshal(b, &r)
+		return filestore.Record{Value: r.Name, DueAt: r.At, Order: r.At}, err
+	}
+
+	tests := []struct {
+		now   time.Time
+		limit int
+		want  []string
+	}{
+		{now, 0, []string{"first", "second"}},
+		{now, 1, []string{"first"}},
+		{time.Time{}, 0, []string{"first", "second", "later"}},
+	}
+	for _, tt := range tests {
+		records, err := filestore.Due(encoded, tt.now, tt.limit, decode)
+		if err != nil {
+			t.Fatalf("Due() error = %v", err)
+		}
+		var got []string
+		for _, r := range records {
+			got = append(got, r.Value.(string))
+		}
+		if diff := cmp.Diff(tt.want, got); diff != "" {
+			t.Errorf("Due(%v, %d) mismatch (-want +got):\n%s", tt.now, tt.limit, diff)
+		}
+	}
+}
@@ -3,6 +3,7 @@
 package kik
 
 import (
+	"context"
 	"crypto/hmac"
 	"crypto/sha1"
 	"encoding/hex"
@@ -20,12 +21,27 @@ const (
 	CodeUrl        = "/v1/code"
 )
 
+// Limits on the number of messages in a single request.
+const (
+	MaxSendMessages      = 25
+	MaxBroadcastMessages = 100
+)
+
+// Headers sent by Kik on every webhook request.
+const (
+	SignatureHeader = "X-Kik-Signature"
+	UsernameHeader  = "X-Kik-Username"
+)
+
 // Client is used to interface with the Kik bot API.
 type Client struct {
 	BotUsername string
 	ApiKey      string
 	Client      *http.Client
 	BaseUrl     *url.URL
+
+	// PreviousApiKeys are still accepted by VerifySignature, so webhooks keep working while ApiKey is rotated.
+	PreviousApiKeys []string
 }
 
 // NewKikClient is a simple convenience constructor for a Client, you do not have to use it.
@@ -50,7 +66,11 @@ func NewKikClient(baseUrl string, botUsername string, apiKey string, httpClient
 }
 
 func (k *Client) SetConfiguration(c *Configuration) error {
-	req, err := k.newRequest("POST", ConfigtUrl, c)
+	return k.setConfiguration(context.Background(), c)
+}
+
+func (k *Client) setConfiguration(ctx context.Context, c *Configuration) error {
+	req, err := k.newRequest(ctx, "POST", ConfigtUrl, c)
 	if err != nil {
 		return err
 	}
@@ -65,7 +85,11 @@ func (k *Client) SetConfiguration(c *Configuration) error {
 }
 
 func (k *Client) GetConfiguration() (*Configuration, error) {
-	req, err := k.newRequest("GET", ConfigtUrl, nil)
+	return k.getConfiguration(context.Background())
+}
+
+func (k *Client) getConfiguration(ctx context.Context) (*Configuration, error) {
+	req, err := k.newRequest(ctx, "GET", ConfigtUrl, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -81,9 +105,15 @@ func (k *Client) GetConfiguration() (*Configuration, error) {
 }
 
 func (k *Client) SendMessage(messages []Message) error {
+	return k.SendMessageContext(context.Background(), messages)
+}
+
+// SendMessageContext is SendMessage with a context, the request is cancelled when ctx is done.
+// Background senders use it so shutting them down doesn't wait for the HTTP client's timeout.
+func (k *Client) SendMessageContext(ctx context.Context, messages []Message) error {
 	payload := Messages{messages}
 
-	req, err := k.newRequest("POST", SendMessageUrl, payload)
+	req, err := k.newRequest(ctx, "POST", SendMessageUrl, payload)
 	if err != nil {
 		return err
 	}
@@ -96,7 +126,7 @@ func (k *Client) SendMessage(messages []Message) error {
 func (k *Client) BroadcastMessage(messages []Message) error {
 	payload := Messages{messages}
 
-	req, err := k.newRequest("POST", BroadcastUrl, payload)
+	req, err := k.newRequest(context.Background(), "POST", BroadcastUrl, payload)
 	if err != nil {
 		return err
 	}
@@ -108,7 +138,11 @@ func (k *Client) BroadcastMessage(messages []Message) error {
 
 // GetUser returns a users profile data as a User struct.
 func (k *Client) GetUser(username string) (*User, error) {
-	req, err := k.newRequest("GET", GetUserUrl+username, nil)
+	return k.getUser(context.Background(), username)
+}
+
+func (k *Client) getUser(ctx context.Context, username string) (*User, error) {
+	req, err := k.newRequest(ctx, "GET", GetUserUrl+username, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -124,7 +158,11 @@ func (k *Client) GetUser(username string) (*User, error) {
 }
 
 func (k *Client) CreateCode(s *ScanData) (*Code, error) {
-	req, err := k.newRequest("POST", CodeUrl, s)
+	return k.createCode(context.Background(), s)
+}
+
+func (k *Client) createCode(ctx context.Context, s *ScanData) (*Code, error) {
+	req, err := k.newRequest(ctx, "POST", CodeUrl, s)
 	if err != nil {
 		return nil, err
 	}
@@ -140,14 +178,25 @@ func (k *Client) CreateCode(s *ScanData) (*Code, error) {
 }
 
 // VerifySignature verifies that a request body correctly matches the header signature.
+// The hex signature may be in either case and is compared in constant time, against ApiKey and PreviousApiKeys.
 // For more on signatures see the [docs](https://dev.kik.com/#/docs/messaging#receiving-messages).
 func (k *Client) VerifySignature(signature string, body []byte) bool {
-	return signature == computeHmac1(body, k.ApiKey)
+	got, err := hex.DecodeString(signature)
+	if err != nil || len(got) != sha1.Size {
+		return false
+	}
+
+	valid := hmac.Equal(got, computeHmac1(body, k.ApiKey))
+	for _, key := range k.PreviousApiKeys {
+		// Every key is checked, so the time taken doesn't reveal which one matched.
+		valid = hmac.Equal(got, computeHmac1(body, key)) || valid
+	}
+	return valid
 }
 
-func computeHmac1(message []byte, secret string) string {
+func computeHmac1(message []byte, secret string) []byte {
 	key := []byte(secret)
 	h := hmac.New(sha1.New, key)
 	h.Write(message)
-	return hex.EncodeToString(h.Sum(nil))
+	return h.Sum(nil)
 }
@@ -2,6 +2,7 @@ package kik_test
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
@@ -76,18 +77,34 @@ func TestGetUser_ShouldFailToDecodeUser(t *testing.T) {
 	}
 }
 
-// TODO: can't verify this is working yet, seems like something on Kik side.
-// func TestVerifySignature_Valid(t *testing.T) {
-// 	client, _, teardown := kiktest.TestClient(t)
-// 	defer teardown()
+// The signature this test used to have, AC18D0105C2C257652859322B0499313342C6EB9, is not the HMAC of "body"
+// under the test key whatever its case, which is why it never passed.
+func TestVerifySignature_Valid(t *testing.T) {
+	client, _, teardown := kiktest.TestClient(t)
+	defer teardown()
 
-// 	got := client.VerifySignature("AC18D0105C2C257652859322B0499313342C6EB9", []byte("body"))
-// 	want := true
+	for _, sig := range []string{"247a341f560ecadfc901923103d5278ee241875d", "247A341F560ECADFC901923103D5278EE241875D"} {
+		if !client.VerifySignature(sig, []byte("body")) {
+			t.Errorf("VerifySignature(%q) = false; want true", sig)
+		}
+	}
+}
+
+func TestVerifySignature_AcceptsPreviousApiKeys(t *testing.T) {
+	client, _, teardown := kiktest.TestClient(t)
+	defer teardown()
 
-// 	if got != want {
-// 		t.Errorf("Expected signature validation to be correct.")
-// 	}
-// }
+	// Signed with the key "test", which is being rotated out.
+	sig := "247a341f560ecadfc901923103d5278ee241875d"
+	client.ApiKey = "new-key"
+	if client.VerifySignature(sig, []byte("body")) {
+		t.Errorf("VerifySignature() = true for a key that was rotated out; want false")
+	}
+	client.PreviousApiKeys = []string{"test"}
+	if !client.VerifySignature(sig, []byte("body")) {
+		t.Errorf("VerifySignature() = false for a previous key; want true")
+	}
+}
 
 func TestVerifySignature_Invalid(t *testing.T) {
 	client, _, teardown := kiktest.TestClient(t)
@@ -99,3 +116,50 @@ func TestVerifySignature_Invalid(t *testing.T) {
 		t.Errorf("Expected signature validation to fail.")
 	}
 }
+
+func TestReceivedMessages_UnknownTypeKeepsCommonFields(t *testing.T) {
+	data := []byte(`{"messages": [
+		{"type": "text", "chatId": "c1", "from": "a", "body": "hi"},
+		{"type": "is-typing", "chatId": "c1", "from": "a"}
+	]}`)
+
+	var got kik.ReceivedMessages
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("Unmarshal returned an error = %+v; expected no error", err)
+	}
+
+	want := kik.ReceivedMessages{
+		&kik.TextMessageReceive{
+			ReceiveMessage: kik.ReceiveMessage{ChatId: "c1", From: "a", Type: "text"},
+			Body:           "hi",
+		},
+		&kik.ReceiveMessage{ChatId: "c1", From: "a", Type: "is-typing"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Unmarshal() = %v; want %v", got, want)
+	}
+}
+
+func TestMessages_RoundTripsThroughJSON(t *testing.T) {
+	want := kik.Messages{Messages: []kik.Message{
+		&kik.TextMessage{SendMessage: kik.SendMessage{To: username, Type: "text"}, Body: "hi"},
+		&kik.LinkMessage{SendMessage: kik.SendMessage{To: username, Type: "link"}, Url: "https://kik.com"},
+	}}
+
+	b, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got kik.Messages
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal returned an error = %+v; expected no error", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("Unmarshal() = %v; want %v", got, want)
+	}
+
+	err = json.Unmarshal([]byte(`{"messages": [{"type": "is-typing"}]}`), &got)
+	if !errors.Is(err, kik.NotMessageTypeError) {
+		t.Errorf("Unmarshal(is-typing) = %v; want %v", err, kik.NotMessageTypeError)
+	}
+}
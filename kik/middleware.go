@@ -0,0 +1,189 @@
+package kik
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Middleware wraps a Handler with behaviour shared by every message, such as logging or access control,
+// keeping it out of the Handler itself.
+type Middleware func(next Handler) Handler
+
+// Chain wraps h in middleware. The first middleware is the outermost, so it sees each message first.
+func Chain(h Handler, middleware ...Middleware) Handler {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		h = middleware[i](h)
+	}
+	return h
+}
+
+// Recover turns a panic in the handler into an error, logging its stack trace.
+func Recover() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, c *Client, m Receive) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("panic handling %T from %s: %v\n%s", m, m.Common().From, r, debug.Stack())
+					err = fmt.Errorf("panic handling %T: %v", m, r)
+				}
+			}()
+			return next.HandleMessage(ctx, c, m)
+		})
+	}
+}
+
+// Logging logs every message with how long it took to handle and the error, if any.
+// A nil logger logs with the standard logger.
+func Logging(logger *log.Logger) Middleware {
+	if logger == nil {
+		logger = log.Default()
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, c *Client, m Receive) error {
+			start := time.Now()
+			err := next.HandleMessage(ctx, c, m)
+			common := m.Common()
+			if err != nil {
+				logger.Printf("%s: %s %s from %s in %s failed after %s: %v", c.BotUsername, common.Type, common.Id, common.From, common.ChatId, time.Since(start), err)
+			} else {
+				logger.Printf("%s: %s %s from %s in %s handled in %s", c.BotUsername, common.Type, common.Id, common.From, common.ChatId, time.Since(start))
+			}
+			return err
+		})
+	}
+}
+
+// AllowFrom only passes on messages from the given users, compared case-insensitively.
+// Messages from anyone else are dropped.
+func AllowFrom(usernames ...string) Middleware {
+	allowed := make(map[string]bool, len(usernames))
+	for _, u := range usernames {
+		allowed[strings.ToLower(u)] = true
+	}
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, c *Client, m Receive) error {
+			if !allowed[strings.ToLower(m.Common().From)] {
+				return nil
+			}
+			return next.HandleMessage(ctx, c, m)
+		})
+	}
+}
+
+// RateLimit passes on at most n messages per user in any period of per, dropping the rest.
+// Short bursts of up to n messages are let through straight away.
+func RateLimit(n int, per time.Duration) Middleware {
+	l := newLimiter(n, per)
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, c *Client, m Receive) error {
+			if !l.allow(strings.ToLower(m.Common().From), time.Now()) {
+				return nil
+			}
+			return next.HandleMessage(ctx, c, m)
+		})
+	}
+}
+
+// ReadReceipts sends a read receipt for every message that asks for one, once it was handled without error.
+// Use it with Features.ManuallySendReadReceipts.
+func ReadReceipts() Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, c *Client, m Receive) error {
+			if err := next.HandleMessage(ctx, c, m); err != nil {
+				return err
+			}
+			common := m.Common()
+			if !common.ReadReceiptRequested || common.Id == "" {
+				return nil
+			}
+			return c.SendMessage([]Message{ReadReceiptMessage{
+				SendMessage: SendMessage{To: common.From, ChatId: common.ChatId, Type: "read-receipt"},
+				MessageIds:  []string{common.Id},
+			}})
+		})
+	}
+}
+
+type languageKey struct{}
+
+// Language stores the language detect returns for each message in the context, see LanguageFromContext.
+// detect returns a language tag such as "en", or "" when it can't tell.
+func Language(detect func(m Receive) string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(ctx context.Context, c *Client, m Receive) error {
+			if lang := detect(m); lang != "" {
+				ctx = context.WithValue(ctx, languageKey{}, lang)
+			}
+			return next.HandleMessage(ctx, c, m)
+		})
+	}
+}
+
+// LanguageFromContext returns the language detected by the Language middleware, or "".
+func LanguageFromContext(ctx context.Context) string {
+	lang, _ := ctx.Value(languageKey{}).(string)
+	return lang
+}
+
+// limiter is a token bucket per key: a key can take up to burst tokens at once,
+// and gets them back at a rate of burst per period.
+type limiter struct {
+	burst  float64
+	period time.Duration
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	at     time.Time
+}
+
+func newLimiter(burst int, period time.Duration) *limiter {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &limiter{burst: float64(burst), period: period, buckets: map[string]*bucket{}}
+}
+
+// allow takes a token for key, reporting false when there is none left.
+func (l *limiter) allow(key string, now time.Time) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Buckets idle for a whole period are full again, so they can be forgotten.
+	if now.Sub(l.lastSweep) > l.period {
+		for k, b := range l.buckets {
+			if now.Sub(b.at) > l.period {
+				delete(l.buckets, k)
+			}
+		}
+		l.lastSweep = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: l.burst, at: now}
+		l.buckets[key] = b
+	}
+	if l.period > 0 {
+		b.tokens += l.burst * float64(now.Sub(b.at)) / float64(l.period)
+		if b.tokens > l.burst {
+			b.tokens = l.burst
+		}
+	}
+	b.at = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
@@ -0,0 +1,110 @@
+package kik_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+func textFrom(from, body string) kik.Receive {
+	return &kik.TextMessageReceive{
+		ReceiveMessage: kik.ReceiveMessage{Id: "m-" + body, ChatId: "c-" + from, From: from, Type: "text"},
+		Body:           body,
+	}
+}
+
+func TestChain_AppliesMiddlewareOutermostFirst(t *testing.T) {
+	var order []string
+	trace := func(name string) kik.Middleware {
+		return func(next kik.Handler) kik.Handler {
+			return kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+				order = append(order, name)
+				return next.HandleMessage(ctx, c, m)
+			})
+		}
+	}
+	h := kik.Chain(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		order = append(order, "handler")
+		return nil
+	}), trace("first"), trace("second"))
+
+	_ = h.HandleMessage(context.Background(), nil, textFrom("alice", "hi"))
+	if got := strings.Join(order, ","); got != "first,second,handler" {
+		t.Errorf("ran %s; want first,second,handler", got)
+	}
+}
+
+func TestRecover_ReturnsPanicAsError(t *testing.T) {
+	h := kik.Chain(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		panic("boom")
+	}), kik.Recover())
+
+	err := h.HandleMessage(context.Background(), nil, textFrom("alice", "hi"))
+	if err == nil || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("HandleMessage() error = %v; want the panic as an error", err)
+	}
+}
+
+func TestAllowFromAndRateLimit_DropMessages(t *testing.T) {
+	var handled []string
+	h := kik.Chain(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		handled = append(handled, m.(*kik.TextMessageReceive).Body)
+		return nil
+	}), kik.AllowFrom("Alice", "bob"), kik.RateLimit(2, time.Hour))
+
+	for _, m := range []kik.Receive{
+		textFrom("alice", "1"), textFrom("mallory", "2"), textFrom("ALICE", "3"), textFrom("alice", "4"), textFrom("bob", "5"),
+	} {
+		if err := h.HandleMessage(context.Background(), nil, m); err != nil {
+			t.Fatalf("HandleMessage() error = %v", err)
+		}
+	}
+	if got := strings.Join(handled, ","); got != "1,3,5" {
+		t.Errorf("handled %s; want 1,3,5", got)
+	}
+}
+
+func TestReadReceipts_SentAfterHandling(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	rec := kiktest.RecordMessages(mux)
+
+	h := kik.Chain(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		return nil
+	}), kik.ReadReceipts())
+
+	requested := textFrom("alice", "hi").(*kik.TextMessageReceive)
+	requested.ReadReceiptRequested = true
+	for _, m := range []kik.Receive{requested, textFrom("bob", "hi")} {
+		if err := h.HandleMessage(context.Background(), client, m); err != nil {
+			t.Fatalf("HandleMessage() error = %v", err)
+		}
+	}
+
+	sent := rec.Messages()
+	if len(sent) != 1 || sent[0]["type"] != "read-receipt" || sent[0]["to"] != "alice" {
+		t.Errorf("sent %v; want one read receipt to alice", sent)
+	}
+}
+
+func TestLanguage_StoresDetectedLanguage(t *testing.T) {
+	var got string
+	h := kik.Chain(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		got = kik.LanguageFromContext(ctx)
+		return nil
+	}), kik.Language(func(m kik.Receive) string {
+		if strings.HasPrefix(m.(*kik.TextMessageReceive).Body, "hola") {
+			return "es"
+		}
+		return ""
+	}))
+
+	_ = h.HandleMessage(context.Background(), nil, textFrom("alice", "hola"))
+	if got != "es" {
+		t.Errorf("LanguageFromContext() = %q; want %q", got, "es")
+	}
+}
@@ -0,0 +1,195 @@
+// Package outbox makes sending messages reliable: replies are written to a Store before they are sent,
+// so a crash right after a handler decides to reply doesn't lose the reply.
+//
+// A background worker, Outbox.Run, delivers stored batches with Client.SendMessageContext, retrying
+// failures according to a kik.RetryPolicy and moving batches that never succeed to a dead letter list.
+// Delivery is at-least-once. Batches whose messages all have an Id are deduplicated by those Ids,
+// so enqueueing the same reply twice, for example when Kik redelivers a webhook, sends it once.
+package outbox
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// NotFoundError is returned by a Store for entries it doesn't hold.
+var NotFoundError = errors.New("outbox entry not found")
+
+// Entry is a batch of messages waiting to be delivered.
+type Entry struct {
+	Id          string       `json:"id"`
+	Messages    kik.Messages `json:"messages"`
+	CreatedAt   time.Time    `json:"createdAt"`
+	NextAttempt time.Time    `json:"nextAttempt"`
+	Attempts    int          `json:"attempts,omitempty"` // Failed attempts so far.
+	LastError   string       `json:"lastError,omitempty"`
+}
+
+// Store persists entries. Implementations must be safe for concurrent use, and Add must be atomic.
+type Store interface {
+	// Add persists a new entry. It returns false, without error, if an entry with the same Id is
+	// pending, dead or was delivered recently.
+	Add(ctx context.Context, e *Entry) (bool, error)
+	// Pending returns up to limit entries with NextAttempt at or before now, oldest first.
+	Pending(ctx context.Context, now time.Time, limit int) ([]*Entry, error)
+	// Update saves the attempt count and schedule of a pending entry.
+	Update(ctx context.Context, e *Entry) error
+	// Ack removes a delivered entry, remembering its Id for deduplication.
+	Ack(ctx context.Context, id string) error
+	// DeadLetter moves an entry that will not be retried out of the pending entries.
+	DeadLetter(ctx context.Context, e *Entry) error
+	// DeadLetters returns every dead entry.
+	DeadLetters(ctx context.Context) ([]*Entry, error)
+}
+
+// Outbox delivers stored message batches.
+type Outbox struct {
+	Client *kik.Client
+	Store  Store
+	Retry  kik.RetryPolicy
+
+	PollInterval time.Duration // How often Run checks for entries to retry, defaults to 1s.
+	BatchSize    int           // Maximum entries delivered per poll, defaults to 100.
+
+	// OnDeadLetter is called when an entry is moved to the dead letters.
+	OnDeadLetter func(e *Entry, err error)
+
+	wake chan struct{}
+}
+
+// New returns an Outbox with the default settings.
+func New(client *kik.Client, store Store) *Outbox {
+	return &Outbox{Client: client, Store: store, wake: make(chan struct{}, 1)}
+}
+
+// Send stores messages for delivery and returns the entry id. Once Send returns the messages
+// will be delivered by Run, even if the process crashes first.
+// It returns the id of the existing entry, without storing anything, for duplicates.
+func (o *Outbox) Send(ctx context.Context, messages ...kik.Message) (string, error) {
+	if len(messages) == 0 {
+		return "", fmt.Errorf("nothing to send")
+	}
+	if len(messages) > kik.MaxSendMessages {
+		return "", fmt.Errorf("an outbox entry can hold at most %d messages, got %d", kik.MaxSendMessages, len(messages))
+	}
+
+	id, err := entryId(messages)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	e := &Entry{Id: id, Messages: kik.Messages{Messages: messages}, CreatedAt: now, NextAttempt: now}
+	if _, err := o.Store.Add(ctx, e); err != nil {
+		return "", err
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+	return id, nil
+}
+
+// Run delivers entries until ctx is done, waking up early whenever Send stores new ones.
+// It returns nil once ctx is done.
+func (o *Outbox) Run(ctx context.Context) error {
+	interval := o.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := o.Flush(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("outbox: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		case <-o.wake:
+		}
+	}
+}
+
+// Flush delivers the entries that are due now and returns how many were delivered.
+// Failed entries are rescheduled or dead lettered, only Store errors are returned.
+func (o *Outbox) Flush(ctx context.Context) (int, error) {
+	limit := o.BatchSize
+	if limit <= 0 {
+		limit = 100
+	}
+	entries, err := o.Store.Pending(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, e := range entries {
+		if ctx.Err() != nil {
+			return delivered, nil
+		}
+
+		sendErr := o.Client.SendMessageContext(ctx, e.Messages.Messages)
+		if ctx.Err() != nil {
+			// The deadline interrupted the send, the entry stays pending for the next Flush.
+			return delivered, nil
+		}
+		if sendErr == nil {
+			delivered++
+			if err := o.Store.Ack(ctx, e.Id); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		e.Attempts++
+		e.LastError = sendErr.Error()
+		if !kik.IsRetryable(sendErr) || o.Retry.Exhausted(e.Attempts) {
+			if err := o.Store.DeadLetter(ctx, e); err != nil {
+				return delivered, err
+			}
+			if o.OnDeadLetter != nil {
+				o.OnDeadLetter(e, sendErr)
+			} else {
+				log.Printf("outbox: dead lettering %s after %d attempts: %v", e.Id, e.Attempts, sendErr)
+			}
+			continue
+		}
+
+		e.NextAttempt = time.Now().Add(o.Retry.Delay(e.Attempts))
+		if err := o.Store.Update(ctx, e); err != nil {
+			return delivered, err
+		}
+	}
+	return delivered, nil
+}
+
+// entryId derives the id from the message Ids when every message has one, so duplicates share it.
+// Otherwise the entry gets a random id and is never deduplicated.
+func entryId(messages []kik.Message) (string, error) {
+	ids := make([]string, 0, len(messages))
+	for _, m := range messages {
+		id := m.Common().Id
+		if id == "" {
+			b := make([]byte, 20)
+			if _, err := rand.Read(b); err != nil {
+				return "", err
+			}
+			return hex.EncodeToString(b), nil
+		}
+		ids = append(ids, id)
+	}
+	sum := sha1.Sum([]byte(strings.Join(ids, "\n")))
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,135 @@
+package outbox_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/outbox"
+	"github.com/4kelly/go-kik/kiktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func reply(id, body string) kik.Message {
+	return kik.TextMessage{SendMessage: kik.SendMessage{To: "alice", Type: "text", Id: id}, Body: body}
+}
+
+func TestOutbox_DeliversOnceAcrossRestarts(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	sent := kiktest.RecordMessages(mux)
+
+	dir, err := ioutil.TempDir("", "outbox")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, _ := outbox.NewFileStore(dir)
+	ctx := context.Background()
+
+	o := outbox.New(client, store)
+	_, _ = o.Send(ctx, reply("m1", "first"))
+	_, _ = o.Send(ctx, reply("m1", "first")) // Redelivered webhook, same reply.
+	_, _ = o.Send(ctx, reply("", "no id"))
+
+	// The process crashed before delivering, a new outbox picks up the stored entries.
+	store, _ = outbox.NewFileStore(dir)
+	o = outbox.New(client, store)
+	if n, err := o.Flush(ctx); err != nil || n != 2 {
+		t.Fatalf("Flush() = %d, %v; want 2 delivered", n, err)
+	}
+	_, _ = o.Send(ctx, reply("m1", "first")) // Delivered already.
+	_, _ = o.Flush(ctx)
+
+	if !cmp.Equal(sent.Bodies(), []string{"first", "no id"}) {
+		t.Errorf("sent %v; want [first no id]", sent.Bodies())
+	}
+}
+
+func TestOutbox_DeadLettersAfterRetries(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	mux.HandleFunc(kik.SendMessageUrl, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	})
+
+	store := outbox.NewMemoryStore()
+	o := outbox.New(client, store)
+	o.Retry = kik.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Nanosecond}
+	ctx := context.Background()
+
+	id, _ := o.Send(ctx, reply("m1", "doomed"))
+	for i := 0; i < 3; i++ {
+		_, _ = o.Flush(ctx)
+	}
+
+	dead, _ := store.DeadLetters(ctx)
+	if len(dead) != 1 || dead[0].Id != id || dead[0].Attempts != 3 {
+		t.Errorf("DeadLetters() = %v; want %s after 3 attempts", dead, id)
+	}
+	if pending, _ := store.Pending(ctx, time.Now(), 0); len(pending) != 0 {
+		t.Errorf("Pending() = %v; want nothing", pending)
+	}
+}
+
+func TestOutbox_FlushStopsAtDeadline(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	// Kik doesn't answer until the test is over, only the deadline ends the send.
+	release := make(chan struct{})
+	defer close(release)
+	mux.HandleFunc(kik.SendMessageUrl, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	store := outbox.NewMemoryStore()
+	o := outbox.New(client, store)
+	id, _ := o.Send(context.Background(), reply("m1", "hanging"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if n, err := o.Flush(ctx); n != 0 || err != nil {
+		t.Fatalf("Flush() = %d, %v; want 0, nil", n, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("Flush() returned after %s; want it to stop at the deadline", d)
+	}
+
+	// The interrupted send isn't an attempt, the entry is delivered by the next Flush.
+	pending, _ := store.Pending(context.Background(), time.Now(), 0)
+	if len(pending) != 1 || pending[0].Id != id || pending[0].Attempts != 0 {
+		t.Errorf("Pending() = %v; want %s with no attempts", pending, id)
+	}
+}
+
+func TestOutbox_RunDeliversInBackground(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	sent := kiktest.RecordMessages(mux)
+
+	o := outbox.New(client, outbox.NewMemoryStore())
+	o.PollInterval = time.Hour // Only Send should wake the worker.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- o.Run(ctx) }()
+
+	_, _ = o.Send(ctx, reply("m1", "hello"))
+	deadline := time.Now().Add(time.Second)
+	for len(sent.Messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	if err := <-done; err != nil {
+		t.Errorf("Run returned an error = %+v; expected no error", err)
+	}
+	if !cmp.Equal(sent.Bodies(), []string{"hello"}) {
+		t.Errorf("sent %v; want [hello]", sent.Bodies())
+	}
+}
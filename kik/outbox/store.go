@@ -0,0 +1,267 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/4kelly/go-kik/kik/internal/filestore"
+)
+
+// defaultRetention is how long delivered ids are remembered for deduplication.
+const defaultRetention = 24 * time.Hour
+
+// MemoryStore keeps entries in memory. It deduplicates, but doesn't survive a crash,
+// so it is meant for tests and for bots that only need the retries.
+type MemoryStore struct {
+	Retention time.Duration // How long delivered ids are remembered, defaults to 24h.
+
+	mu        sync.Mutex
+	pending   map[string][]byte
+	dead      map[string][]byte
+	delivered map[string]time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		pending:   map[string][]byte{},
+		dead:      map[string][]byte{},
+		delivered: map[string]time.Time{},
+	}
+}
+
+func (s *MemoryStore) Add(ctx context.Context, e *Entry) (bool, error) {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, pending := s.pending[e.Id]
+	_, dead := s.dead[e.Id]
+	_, delivered := s.delivered[e.Id]
+	if pending || dead || delivered {
+		return false, nil
+	}
+	s.pending[e.Id] = b
+	return true, nil
+}
+
+func (s *MemoryStore) Pending(ctx context.Context, now time.Time, limit int) ([]*Entry, error) {
+	s.mu.Lock()
+	encoded := make([][]byte, 0, len(s.pending))
+	for _, b := range s.pending {
+		encoded = append(encoded, b)
+	}
+	s.mu.Unlock()
+	return due(encoded, now, limit)
+}
+
+func (s *MemoryStore) Update(ctx context.Context, e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.pending[e.Id]; !ok {
+		return NotFoundError
+	}
+	s.pending[e.Id] = b
+	return nil
+}
+
+func (s *MemoryStore) Ack(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	retention := s.Retention
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	for k, at := range s.delivered {
+		if now.Sub(at) > retention {
+			delete(s.delivered, k)
+		}
+	}
+
+	delete(s.pending, id)
+	s.delivered[id] = now
+	return nil
+}
+
+func (s *MemoryStore) DeadLetter(ctx context.Context, e *Entry) error {
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, e.Id)
+	s.dead[e.Id] = b
+	return nil
+}
+
+func (s *MemoryStore) DeadLetters(ctx context.Context) ([]*Entry, error) {
+	s.mu.Lock()
+	encoded := make([][]byte, 0, len(s.dead))
+	for _, b := range s.dead {
+		encoded = append(encoded, b)
+	}
+	s.mu.Unlock()
+	return due(encoded, time.Time{}, 0)
+}
+
+// FileStore keeps every entry in its own JSON file, under pending/ and dead/ in a directory.
+// Delivered ids are remembered as empty files under delivered/.
+type FileStore struct {
+	Dir       string
+	Retention time.Duration // How long delivered ids are remembered, defaults to 24h.
+
+	mu        sync.Mutex
+	lastPrune time.Time
+}
+
+// NewFileStore returns a FileStore writing to dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	for _, sub := range []string{"pending", "dead", "delivered"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o700); err != nil {
+			return nil, err
+		}
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (s *FileStore) Add(ctx context.Context, e *Entry) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sub := range []string{"pending", "dead", "delivered"} {
+		if _, err := os.Stat(s.path(sub, e.Id)); err == nil {
+			return false, nil
+		}
+	}
+	return true, s.write("pending", e)
+}
+
+func (s *FileStore) Pending(ctx context.Context, now time.Time, limit int) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded, err := s.readAll("pending")
+	if err != nil {
+		return nil, err
+	}
+	return due(encoded, now, limit)
+}
+
+func (s *FileStore) Update(ctx context.Context, e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := os.Stat(s.path("pending", e.Id)); os.IsNotExist(err) {
+		return NotFoundError
+	}
+	return s.write("pending", e)
+}
+
+func (s *FileStore) Ack(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := ioutil.WriteFile(s.path("delivered", id), nil, 0o600); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path("pending", id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.prune()
+}
+
+func (s *FileStore) DeadLetter(ctx context.Context, e *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.write("dead", e); err != nil {
+		return err
+	}
+	if err := os.Remove(s.path("pending", e.Id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *FileStore) DeadLetters(ctx context.Context) ([]*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded, err := s.readAll("dead")
+	if err != nil {
+		return nil, err
+	}
+	return due(encoded, time.Time{}, 0)
+}
+
+func (s *FileStore) write(sub string, e *Entry) error {
+	return filestore.WriteJSON(s.path(sub, e.Id), e)
+}
+
+func (s *FileStore) readAll(sub string) ([][]byte, error) {
+	return filestore.ReadAll(filepath.Join(s.Dir, sub))
+}
+
+// prune forgets delivered ids older than the retention, at most once a minute.
+func (s *FileStore) prune() error {
+	if time.Since(s.lastPrune) < time.Minute {
+		return nil
+	}
+	s.lastPrune = time.Now()
+
+	retention := s.Retention
+	if retention <= 0 {
+		retention = defaultRetention
+	}
+	files, err := ioutil.ReadDir(filepath.Join(s.Dir, "delivered"))
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if time.Since(f.ModTime()) > retention {
+			_ = os.Remove(filepath.Join(s.Dir, "delivered", f.Name()))
+		}
+	}
+	return nil
+}
+
+// path is only ever given ids made by entryId, which are hex.
+func (s *FileStore) path(sub, id string) string {
+	return filepath.Join(s.Dir, sub, filepath.Base(id)+".json")
+}
+
+// due decodes the entries due at now, oldest first. A zero now returns every entry.
+func due(encoded [][]byte, now time.Time, limit int) ([]*Entry, error) {
+	records, err := filestore.Due(encoded, now, limit, func(b []byte) (filestore.Record, error) {
+		var e Entry
+		if err := json.Unmarshal(b, &e); err != nil {
+			return filestore.Record{}, err
+		}
+		return filestore.Record{Value: &e, DueAt: e.NextAttempt, Order: e.CreatedAt}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]*Entry, len(records))
+	for i, r := range records {
+		entries[i] = r.Value.(*Entry)
+	}
+	return entries, nil
+}
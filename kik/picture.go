@@ -0,0 +1,133 @@
+package kik
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Registers decoders for the formats profile pictures come in.
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+)
+
+// PictureSize is the width and height in pixels of a square thumbnail.
+type PictureSize int
+
+// Standard thumbnail sizes. PictureSizeOriginal keeps the picture as Kik serves it.
+const (
+	PictureSizeOriginal PictureSize = 0
+	PictureSizeSmall    PictureSize = 48
+	PictureSizeMedium   PictureSize = 128
+	PictureSizeLarge    PictureSize = 256
+)
+
+// pictureTypes are the content types accepted as profile pictures.
+var pictureTypes = map[string]bool{
+	"image/jpeg": true,
+	"image/png":  true,
+	"image/gif":  true,
+}
+
+// Limits on downloaded pictures, so a broken or hostile URL can't exhaust memory.
+const (
+	maxPictureBytes  = 10 << 20    // Size of the file.
+	maxPicturePixels = 4096 * 4096 // Width times height of the decoded image.
+)
+
+// GetProfilePicture looks up a user and downloads their profile picture through the Client's HTTP client.
+// The picture is scaled down to fit within size, keeping its aspect ratio. It is never scaled up.
+func (k *Client) GetProfilePicture(ctx context.Context, username string, size PictureSize) (image.Image, error) {
+	user, err := k.getUser(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	if user.ProfilePicUrl == "" {
+		return nil, fmt.Errorf("user %s has no profile picture", username)
+	}
+
+	b, err := k.downloadPicture(ctx, user.ProfilePicUrl)
+	if err != nil {
+		return nil, err
+	}
+	config, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode profile picture of %s: %w", username, err)
+	}
+	if int64(config.Width)*int64(config.Height) > maxPicturePixels {
+		return nil, fmt.Errorf("profile picture of %s is %dx%d, larger than %d pixels", username, config.Width, config.Height, maxPicturePixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode profile picture of %s: %w", username, err)
+	}
+	return Thumbnail(img, size), nil
+}
+
+// downloadPicture fetches an image of at most maxPictureBytes and checks it is one of the pictureTypes.
+// The type is always sniffed from the body, the Content-Type header isn't trusted.
+func (k *Client) downloadPicture(ctx context.Context, url string) ([]byte, error) {
+	req, err := k.newRequest(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	b, _, err := k.doRaw(req, maxPictureBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType := http.DetectContentType(b); !pictureTypes[contentType] {
+		return nil, fmt.Errorf("expected a picture from %s, got %q", url, contentType)
+	}
+	return b, nil
+}
+
+// Thumbnail scales img down to fit within a size x size square, keeping its aspect ratio.
+// Each pixel of the result is the average of the pixels it covers. Images that already fit,
+// and PictureSizeOriginal, are returned unchanged.
+func Thumbnail(img image.Image, size PictureSize) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	s := int(size)
+	if s <= 0 || (w <= s && h <= s) {
+		return img
+	}
+
+	tw, th := s, s
+	if w > h {
+		th = h * s / w
+	} else {
+		tw = w * s / h
+	}
+	if tw < 1 {
+		tw = 1
+	}
+	if th < 1 {
+		th = 1
+	}
+
+	out := image.NewRGBA(image.Rect(0, 0, tw, th))
+	for y := 0; y < th; y++ {
+		y0, y1 := b.Min.Y+y*h/th, b.Min.Y+(y+1)*h/th
+		for x := 0; x < tw; x++ {
+			x0, x1 := b.Min.X+x*w/tw, b.Min.X+(x+1)*w/tw
+
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					pr, pg, pb, pa := img.At(sx, sy).RGBA()
+					r, g, bl, a = r+uint64(pr), g+uint64(pg), bl+uint64(pb), a+uint64(pa)
+					n++
+				}
+			}
+			out.SetRGBA(x, y, color.RGBA{
+				R: uint8(r / n >> 8),
+				G: uint8(g / n >> 8),
+				B: uint8(bl / n >> 8),
+				A: uint8(a / n >> 8),
+			})
+		}
+	}
+	return out
+}
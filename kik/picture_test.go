@@ -0,0 +1,94 @@
+package kik_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+func TestGetProfilePicture_ScalesToSize(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	mux.HandleFunc(kik.GetUserUrl, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"profilePicUrl": "%spic/%s"}`,
+			client.BaseUrl, strings.TrimPrefix(r.URL.Path, kik.GetUserUrl))
+	})
+	mux.HandleFunc("/pic/", func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/html"):
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, "<html></html>")
+			return
+		case strings.HasSuffix(r.URL.Path, "/spoofed"):
+			w.Header().Set("Content-Type", "image/png")
+			fmt.Fprint(w, "<html></html>")
+			return
+		case strings.HasSuffix(r.URL.Path, "/huge"):
+			w.Write(pngHeader(t, 100000, 100000))
+			return
+		case strings.HasSuffix(r.URL.Path, "/long"):
+			w.Write(append(pngBytes(t, 1, 1), make([]byte, 10<<20)...))
+			return
+		}
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngBytes(t, 300, 150))
+	})
+
+	tests := []struct {
+		size kik.PictureSize
+		want image.Point
+	}{
+		{kik.PictureSizeOriginal, image.Pt(300, 150)},
+		{kik.PictureSizeMedium, image.Pt(128, 64)},
+		{kik.PictureSizeSmall, image.Pt(48, 24)},
+	}
+	for _, tt := range tests {
+		img, err := client.GetProfilePicture(context.Background(), username, tt.size)
+		if err != nil {
+			t.Fatalf("GetProfilePicture(%d) returned an error = %+v; expected no error", tt.size, err)
+		}
+		if got := img.Bounds().Size(); got != tt.want {
+			t.Errorf("GetProfilePicture(%d) size = %v; want %v", tt.size, got, tt.want)
+		}
+	}
+
+	for _, username := range []string{"html", "spoofed", "huge", "long"} {
+		if _, err := client.GetProfilePicture(context.Background(), username, kik.PictureSizeSmall); err == nil {
+			t.Errorf("GetProfilePicture() of %s returned no error", username)
+		}
+	}
+}
+
+// pngHeader returns a PNG that claims to be w x h, without the pixels to back it up.
+func pngHeader(t *testing.T, w, h uint32) []byte {
+	b := pngBytes(t, 1, 1)
+	// The IHDR chunk follows the 8 byte signature: length, type, width, height, then 5 more bytes and the CRC.
+	binary.BigEndian.PutUint32(b[16:], w)
+	binary.BigEndian.PutUint32(b[20:], h)
+	binary.BigEndian.PutUint32(b[29:], crc32.ChecksumIEEE(b[12:29]))
+	return b
+}
+
+func TestThumbnail_AveragesPixels(t *testing.T) {
+	var buf bytes.Buffer
+	src := image.NewGray(image.Rect(0, 0, 2, 2))
+	src.Pix = []uint8{0, 255, 255, 0}
+	_ = png.Encode(&buf, src)
+	img, _ := png.Decode(&buf)
+
+	r, _, _, _ := kik.Thumbnail(img, 1).At(0, 0).RGBA()
+	if r>>8 != 127 {
+		t.Errorf("Thumbnail() pixel = %d; want 127", r>>8)
+	}
+}
@@ -0,0 +1,122 @@
+package kik
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RegistryEntry is a single bot managed by a Registry.
+type RegistryEntry struct {
+	Client  *Client // Holds the bot username, API key and HTTP client settings.
+	Handler Handler // Receives the messages sent to this bot.
+
+	Configuration *Configuration // Optional, written by Registry.Configure.
+	Webhook       *Webhook       // Optional, defaults to NewWebhook(Client, Handler).
+}
+
+// Registry manages many bot accounts behind a single webhook endpoint.
+// Requests are routed to the right bot using the X-Kik-Username header and verified with that bot's API key.
+// Bot usernames are case-insensitive.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]*RegistryEntry
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: map[string]*RegistryEntry{}}
+}
+
+// Register adds a bot to the registry. It fails if the bot is already registered.
+func (r *Registry) Register(e *RegistryEntry) error {
+	if e.Client == nil || e.Client.BotUsername == "" {
+		return fmt.Errorf("registry entry must have a Client with a BotUsername")
+	}
+	if e.Webhook == nil {
+		if e.Handler == nil {
+			return fmt.Errorf("registry entry for %s must have a Handler or Webhook", e.Client.BotUsername)
+		}
+		e.Webhook = NewWebhook(e.Client, e.Handler)
+	}
+
+	key := strings.ToLower(e.Client.BotUsername)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.entries[key]; ok {
+		return fmt.Errorf("bot %s is already registered", e.Client.BotUsername)
+	}
+	r.entries[key] = e
+	return nil
+}
+
+// Remove drops a bot from the registry, it is a no-op for unknown bots.
+func (r *Registry) Remove(botUsername string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, strings.ToLower(botUsername))
+}
+
+// Get returns the entry for a bot.
+func (r *Registry) Get(botUsername string) (*RegistryEntry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.entries[strings.ToLower(botUsername)]
+	return e, ok
+}
+
+// Usernames returns the usernames of all registered bots, sorted.
+func (r *Registry) Usernames() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	names := make([]string, 0, len(r.entries))
+	for _, e := range r.entries {
+		names = append(names, e.Client.BotUsername)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Configure applies the Configuration of every entry that has one, see Client.ApplyConfiguration.
+// It carries on past failing bots and returns the changes per bot along with the first error.
+func (r *Registry) Configure(ctx context.Context, dryRun bool) (map[string][]ConfigurationChange, error) {
+	r.mu.RLock()
+	entries := make([]*RegistryEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+	r.mu.RUnlock()
+
+	changes := map[string][]ConfigurationChange{}
+	var firstErr error
+	for _, e := range entries {
+		if e.Configuration == nil {
+			continue
+		}
+		c, err := e.Client.ApplyConfiguration(ctx, e.Configuration, dryRun)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", e.Client.BotUsername, err)
+			}
+			continue
+		}
+		changes[e.Client.BotUsername] = c
+	}
+	return changes, firstErr
+}
+
+// ServeHTTP routes a webhook request to the Webhook of the bot named in the X-Kik-Username header.
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	e, ok := r.Get(req.Header.Get(UsernameHeader))
+	if !ok {
+		http.Error(w, UnknownBotError.Error(), http.StatusNotFound)
+		return
+	}
+	e.Webhook.ServeHTTP(w, req)
+}
@@ -0,0 +1,68 @@
+package kik_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+const textPayload = `{"messages": [{"type": "text", "chatId": "c1", "id": "m1", "from": "alice", "body": "hi"}]}`
+
+func TestRegistry_RoutesByUsernameHeader(t *testing.T) {
+	registry := kik.NewRegistry()
+
+	got := map[string]int{}
+	for _, name := range []string{"botOne", "botTwo"} {
+		c, err := kik.NewKikClient("https://api.kik.com/", name, name+"-key", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = registry.Register(&kik.RegistryEntry{
+			Client: c,
+			Handler: kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+				got[c.BotUsername]++
+				return nil
+			}),
+		})
+		if err != nil {
+			t.Fatalf("Register(%s) returned an error = %+v; expected no error", name, err)
+		}
+	}
+
+	botTwo, _ := registry.Get("BOTTWO")
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, kiktest.WebhookRequest(botTwo.Client, textPayload))
+
+	if w.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() status = %d; want %d", w.Code, http.StatusOK)
+	}
+	if got["botTwo"] != 1 || got["botOne"] != 0 {
+		t.Errorf("handled messages = %v; want only botTwo", got)
+	}
+}
+
+func TestRegistry_RejectsSignatureFromAnotherBot(t *testing.T) {
+	registry := kik.NewRegistry()
+	one, _ := kik.NewKikClient("https://api.kik.com/", "botOne", "key-one", nil)
+	two, _ := kik.NewKikClient("https://api.kik.com/", "botTwo", "key-two", nil)
+	noop := kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error { return nil })
+	_ = registry.Register(&kik.RegistryEntry{Client: one, Handler: noop})
+
+	// Signed by botTwo but addressed to botOne.
+	req := kiktest.WebhookRequest(two, textPayload)
+	req.Header.Set(kik.UsernameHeader, "botOne")
+	w := httptest.NewRecorder()
+	registry.ServeHTTP(w, req)
+
+	if w.Code != http.StatusForbidden {
+		t.Errorf("ServeHTTP() status = %d; want %d", w.Code, http.StatusForbidden)
+	}
+
+	if err := registry.Register(&kik.RegistryEntry{Client: one, Handler: noop}); err == nil {
+		t.Errorf("Register() of a duplicate bot returned no error")
+	}
+}
@@ -0,0 +1,82 @@
+package kik
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ReplayError = errors.New("webhook request was already received")
+var StaleMessageError = errors.New("message timestamp is outside the allowed skew")
+
+// ReplayGuard rejects signed webhook requests that were captured and sent again.
+// A valid signature only proves Kik made the request once, so the guard also requires every
+// message Timestamp to be within MaxSkew of now and remembers the requests it let through.
+// Set it on a Webhook next to the signature check.
+type ReplayGuard struct {
+	Store   SeenStore     // Remembers recent requests, keyed by a hash of the signature and body.
+	MaxSkew time.Duration // How far a message Timestamp may be from now, defaults to 5m.
+
+	// OnReject is called with every rejection, wrapping ReplayError or StaleMessageError.
+	OnReject func(err error)
+}
+
+// NewReplayGuard returns a ReplayGuard remembering up to size requests in memory.
+func NewReplayGuard(maxSkew time.Duration, size int) *ReplayGuard {
+	return &ReplayGuard{Store: NewMemorySeenStore(size), MaxSkew: maxSkew}
+}
+
+// Check returns an error if a request was seen before or any of its messages is stale.
+// Requests are remembered for twice the skew, by then their timestamps reject them anyway.
+// Messages without a Timestamp are stale.
+func (g *ReplayGuard) Check(ctx context.Context, signature string, body []byte, messages ReceivedMessages) error {
+	skew := g.skew()
+	now := time.Now()
+
+	for _, m := range messages {
+		sent := time.Unix(0, int64(m.Common().Timestamp)*int64(time.Millisecond))
+		if d := now.Sub(sent); d > skew || d < -skew {
+			return g.reject(fmt.Errorf("%w: message %s sent at %s", StaleMessageError, m.Common().Id, sent.UTC().Format(time.RFC3339)))
+		}
+	}
+
+	seen, err := g.Store.MarkSeen(ctx, requestKey(signature, body), 2*skew)
+	if err != nil {
+		return err
+	}
+	if seen {
+		return g.reject(fmt.Errorf("%w: signature %s", ReplayError, signature))
+	}
+	return nil
+}
+
+// Forget lets a request through again, used when handling it failed and Kik will redeliver it.
+func (g *ReplayGuard) Forget(ctx context.Context, signature string, body []byte) error {
+	return g.Store.Forget(ctx, requestKey(signature, body))
+}
+
+func (g *ReplayGuard) skew() time.Duration {
+	if g.MaxSkew <= 0 {
+		return 5 * time.Minute
+	}
+	return g.MaxSkew
+}
+
+func (g *ReplayGuard) reject(err error) error {
+	if g.OnReject != nil {
+		g.OnReject(err)
+	}
+	return err
+}
+
+func requestKey(signature string, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(strings.ToLower(signature)))
+	h.Write([]byte{0})
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
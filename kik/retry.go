@@ -0,0 +1,54 @@
+package kik
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// RetryPolicy decides how often, and how long after a failure, a send is tried again.
+// The zero value tries 5 times, waiting 1s before the first retry and doubling the delay up to 1h.
+type RetryPolicy struct {
+	MaxAttempts int           // Attempts including the first one.
+	BaseDelay   time.Duration // Delay before the first retry, doubled for every retry after it.
+	MaxDelay    time.Duration // Upper bound for the delay.
+}
+
+// Delay returns how long to wait after the given number of failed attempts.
+func (p RetryPolicy) Delay(failures int) time.Duration {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = time.Second
+	}
+	if max <= 0 {
+		max = time.Hour
+	}
+
+	d := base
+	for i := 1; i < failures && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	return d
+}
+
+// Exhausted reports whether no attempts are left after the given number of failed attempts.
+func (p RetryPolicy) Exhausted(failures int) bool {
+	max := p.MaxAttempts
+	if max <= 0 {
+		max = 5
+	}
+	return failures >= max
+}
+
+// IsRetryable reports whether a failed request may succeed if sent again.
+// Requests the Kik API rejected as invalid (4xx other than 429) will fail the same way every time.
+func IsRetryable(err error) bool {
+	var apiErr *ApiError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500 || apiErr.StatusCode == http.StatusTooManyRequests
+	}
+	return !errors.Is(err, NotMessageTypeError)
+}
@@ -0,0 +1,132 @@
+package kik
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// scanPayloadPrefix marks ScanData written by EncodeScanData, so it can be told apart from plain strings.
+const scanPayloadPrefix = "kik1:"
+
+// ScanPayload is structured data embedded in a Kik Code, used to tell which campaign drove a chat.
+type ScanPayload struct {
+	Campaign string            `json:"c"`
+	Source   string            `json:"s,omitempty"` // Where the code was placed, e.g. a poster location.
+	Values   map[string]string `json:"v,omitempty"`
+}
+
+// EncodeScanData encodes a payload into ScanData for CreateCode.
+// Keys are kept short since the data has to fit into the Kik Code.
+func EncodeScanData(p ScanPayload) (*ScanData, error) {
+	if p.Campaign == "" {
+		return nil, fmt.Errorf("scan payload must have a campaign")
+	}
+	b, err := json.Marshal(p)
+	if err != nil {
+		return nil, err
+	}
+	return &ScanData{Data: scanPayloadPrefix + string(b)}, nil
+}
+
+// DecodeScanData decodes data written by EncodeScanData.
+// It returns NotScanPayloadError for data that was not, such as plain strings.
+func DecodeScanData(data string) (*ScanPayload, error) {
+	if !strings.HasPrefix(data, scanPayloadPrefix) {
+		return nil, NotScanPayloadError
+	}
+
+	var p ScanPayload
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(data, scanPayloadPrefix)), &p); err != nil {
+		return nil, fmt.Errorf("could not decode scan payload: %w", err)
+	}
+	return &p, nil
+}
+
+// Payload decodes the structured payload of the scanned Kik Code, see DecodeScanData.
+func (m *ScanDataReceive) Payload() (*ScanPayload, error) {
+	return DecodeScanData(m.Data)
+}
+
+// ScanCounter records which campaigns users scanned. Implement it to count into your own metrics or database.
+type ScanCounter interface {
+	CountScan(ctx context.Context, p *ScanPayload, m *ScanDataReceive) error
+}
+
+// CampaignScans is the number of scans of a campaign from one source.
+type CampaignScans struct {
+	Campaign string
+	Source   string
+	Scans    int
+}
+
+// MemoryScanCounter counts scans per campaign and source in memory. The zero value is ready to use.
+type MemoryScanCounter struct {
+	mu     sync.Mutex
+	counts map[[2]string]int
+}
+
+// NewMemoryScanCounter returns a MemoryScanCounter with no scans.
+func NewMemoryScanCounter() *MemoryScanCounter {
+	return &MemoryScanCounter{counts: map[[2]string]int{}}
+}
+
+func (c *MemoryScanCounter) CountScan(ctx context.Context, p *ScanPayload, m *ScanDataReceive) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = map[[2]string]int{}
+	}
+	c.counts[[2]string{p.Campaign, p.Source}]++
+	return nil
+}
+
+// Count returns the scans of a campaign across all sources.
+func (c *MemoryScanCounter) Count(campaign string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	total := 0
+	for k, n := range c.counts {
+		if k[0] == campaign {
+			total += n
+		}
+	}
+	return total
+}
+
+// Counts returns the scans of every campaign and source, sorted by campaign then source.
+func (c *MemoryScanCounter) Counts() []CampaignScans {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	out := make([]CampaignScans, 0, len(c.counts))
+	for k, n := range c.counts {
+		out = append(out, CampaignScans{Campaign: k[0], Source: k[1], Scans: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Campaign != out[j].Campaign {
+			return out[i].Campaign < out[j].Campaign
+		}
+		return out[i].Source < out[j].Source
+	})
+	return out
+}
+
+// TrackScans returns a Handler that counts every scan-data message carrying a ScanPayload before calling next.
+// Scans of codes without a valid payload are passed on uncounted, redelivering them wouldn't help.
+func TrackScans(counter ScanCounter, next Handler) Handler {
+	return HandlerFunc(func(ctx context.Context, c *Client, m Receive) error {
+		if scan, ok := m.(*ScanDataReceive); ok {
+			if p, err := scan.Payload(); err == nil {
+				if err := counter.CountScan(ctx, p, scan); err != nil {
+					return err
+				}
+			}
+		}
+		return next.HandleMessage(ctx, c, m)
+	})
+}
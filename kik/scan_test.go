@@ -0,0 +1,82 @@
+package kik_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestScanData_RoundTripsThroughScanDataMessage(t *testing.T) {
+	want := kik.ScanPayload{
+		Campaign: "spring-sale",
+		Source:   "poster-42",
+		Values:   map[string]string{"city": "Toronto"},
+	}
+	scanData, err := kik.EncodeScanData(want)
+	if err != nil {
+		t.Fatalf("EncodeScanData returned an error = %+v; expected no error", err)
+	}
+
+	raw, _ := json.Marshal(map[string]interface{}{
+		"messages": []map[string]string{{"type": "scan-data", "from": "alice", "data": scanData.Data}},
+	})
+	var messages kik.ReceivedMessages
+	if err := json.Unmarshal(raw, &messages); err != nil {
+		t.Fatalf("Unmarshal returned an error = %+v; expected no error", err)
+	}
+
+	scan, ok := messages[0].(*kik.ScanDataReceive)
+	if !ok {
+		t.Fatalf("Unmarshal() = %T; want *kik.ScanDataReceive", messages[0])
+	}
+	got, err := scan.Payload()
+	if err != nil {
+		t.Fatalf("Payload returned an error = %+v; expected no error", err)
+	}
+	if !cmp.Equal(*got, want) {
+		t.Errorf("Payload() = %v; want %v", *got, want)
+	}
+
+	if _, err := kik.DecodeScanData("plain string"); err != kik.NotScanPayloadError {
+		t.Errorf("DecodeScanData(plain string) = %v; want %v", err, kik.NotScanPayloadError)
+	}
+}
+
+func TestTrackScans_CountsPerCampaign(t *testing.T) {
+	counter := kik.NewMemoryScanCounter()
+	handled := 0
+	h := kik.TrackScans(counter, kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		handled++
+		return nil
+	}))
+
+	for _, source := range []string{"poster-1", "poster-2", "poster-1"} {
+		data, _ := kik.EncodeScanData(kik.ScanPayload{Campaign: "spring", Source: source})
+		_ = h.HandleMessage(context.Background(), nil, &kik.ScanDataReceive{Data: data.Data})
+	}
+	_ = h.HandleMessage(context.Background(), nil, &kik.ScanDataReceive{Data: "opaque"})
+
+	want := []kik.CampaignScans{
+		{Campaign: "spring", Source: "poster-1", Scans: 2},
+		{Campaign: "spring", Source: "poster-2", Scans: 1},
+	}
+	if !cmp.Equal(counter.Counts(), want) {
+		t.Errorf("Counts() = %v; want %v", counter.Counts(), want)
+	}
+	if counter.Count("spring") != 3 || handled != 4 {
+		t.Errorf("Count(spring) = %d, handled = %d; want 3, 4", counter.Count("spring"), handled)
+	}
+}
+
+func TestMemoryScanCounter_ZeroValue(t *testing.T) {
+	counter := &kik.MemoryScanCounter{}
+	if err := counter.CountScan(context.Background(), &kik.ScanPayload{Campaign: "spring"}, &kik.ScanDataReceive{}); err != nil {
+		t.Fatalf("CountScan() error = %v", err)
+	}
+	if counter.Count("spring") != 1 {
+		t.Errorf("Count(spring) = %d; want 1", counter.Count("spring"))
+	}
+}
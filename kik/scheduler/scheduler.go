@@ -0,0 +1,170 @@
+// Package scheduler delivers messages at a later time, for reminders and drip campaigns that
+// outlast the few seconds SendMessage.Delay covers.
+//
+// Scheduled messages are persisted through a Store so they survive restarts, and are sent by
+// Scheduler.Run with Client.SendMessageContext, retrying failures according to a kik.RetryPolicy.
+package scheduler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// NotFoundError is returned for jobs that don't exist, or were already sent or cancelled.
+var NotFoundError = errors.New("scheduled job not found")
+
+// Job is a batch of messages to send at a given time.
+type Job struct {
+	Id       string       `json:"id"`
+	RunAt    time.Time    `json:"runAt"` // When to send next, pushed back after every failed attempt.
+	Messages kik.Messages `json:"messages"`
+
+	Attempts  int    `json:"attempts,omitempty"` // Failed attempts so far.
+	LastError string `json:"lastError,omitempty"`
+}
+
+// Store persists jobs. Implementations must be safe for concurrent use.
+type Store interface {
+	Put(ctx context.Context, j *Job) error
+	// Update saves a job that is still stored, returning NotFoundError if it was deleted.
+	Update(ctx context.Context, j *Job) error
+	// Get returns a job, or NotFoundError.
+	Get(ctx context.Context, id string) (*Job, error)
+	// Delete removes a job, returning NotFoundError if it doesn't exist.
+	Delete(ctx context.Context, id string) error
+	// Due returns up to limit jobs with RunAt at or before now, earliest first.
+	Due(ctx context.Context, now time.Time, limit int) ([]*Job, error)
+}
+
+// Scheduler sends scheduled jobs once they are due.
+type Scheduler struct {
+	Client *kik.Client
+	Store  Store
+	Retry  kik.RetryPolicy
+
+	PollInterval time.Duration // How often Run checks for due jobs, defaults to 1s.
+	BatchSize    int           // Maximum jobs sent per poll, defaults to 100.
+
+	// OnFailure is called when a job is dropped after its last attempt, or because it can't succeed.
+	OnFailure func(j *Job, err error)
+}
+
+// New returns a Scheduler with the default settings.
+func New(client *kik.Client, store Store) *Scheduler {
+	return &Scheduler{Client: client, Store: store}
+}
+
+// Schedule persists messages to be sent at the given time and returns the job id, used to Cancel it.
+func (s *Scheduler) Schedule(ctx context.Context, at time.Time, messages ...kik.Message) (string, error) {
+	if len(messages) == 0 {
+		return "", fmt.Errorf("nothing to schedule")
+	}
+	if len(messages) > kik.MaxSendMessages {
+		return "", fmt.Errorf("a job can hold at most %d messages, got %d", kik.MaxSendMessages, len(messages))
+	}
+
+	id, err := newId()
+	if err != nil {
+		return "", err
+	}
+	j := &Job{Id: id, RunAt: at, Messages: kik.Messages{Messages: messages}}
+	if err := s.Store.Put(ctx, j); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// Cancel removes a job that hasn't been sent yet.
+func (s *Scheduler) Cancel(ctx context.Context, id string) error {
+	return s.Store.Delete(ctx, id)
+}
+
+// Run sends due jobs until ctx is done. It returns nil once ctx is done.
+func (s *Scheduler) Run(ctx context.Context) error {
+	interval := s.PollInterval
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := s.RunDue(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("scheduler: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue sends the jobs that are due now and returns how many were sent.
+// Failed jobs are rescheduled or dropped, only Store errors are returned.
+func (s *Scheduler) RunDue(ctx context.Context) (int, error) {
+	limit := s.BatchSize
+	if limit <= 0 {
+		limit = 100
+	}
+	jobs, err := s.Store.Due(ctx, time.Now(), limit)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, j := range jobs {
+		if ctx.Err() != nil {
+			return sent, nil
+		}
+
+		sendErr := s.Client.SendMessageContext(ctx, j.Messages.Messages)
+		if ctx.Err() != nil {
+			// Shutting down interrupted the send, the job stays due and is sent on the next run.
+			return sent, nil
+		}
+		if sendErr == nil {
+			sent++
+			if err := s.Store.Delete(ctx, j.Id); err != nil && !errors.Is(err, NotFoundError) {
+				return sent, err
+			}
+			continue
+		}
+
+		j.Attempts++
+		j.LastError = sendErr.Error()
+		if !kik.IsRetryable(sendErr) || s.Retry.Exhausted(j.Attempts) {
+			if err := s.Store.Delete(ctx, j.Id); err != nil && !errors.Is(err, NotFoundError) {
+				return sent, err
+			}
+			if s.OnFailure != nil {
+				s.OnFailure(j, sendErr)
+			} else {
+				log.Printf("scheduler: dropping job %s after %d attempts: %v", j.Id, j.Attempts, sendErr)
+			}
+			continue
+		}
+
+		// Update rather than Put, so a job cancelled while it was being sent stays cancelled.
+		j.RunAt = time.Now().Add(s.Retry.Delay(j.Attempts))
+		if err := s.Store.Update(ctx, j); err != nil && !errors.Is(err, NotFoundError) {
+			return sent, err
+		}
+	}
+	return sent, nil
+}
+
+func newId() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,145 @@
+package scheduler_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/scheduler"
+	"github.com/4kelly/go-kik/kiktest"
+	"github.com/google/go-cmp/cmp"
+)
+
+func reminder(body string) kik.Message {
+	return kik.TextMessage{SendMessage: kik.SendMessage{To: "alice", Type: "text"}, Body: body}
+}
+
+func TestScheduler_SendsDueJobsAcrossRestarts(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	sent := kiktest.RecordMessages(mux)
+
+	dir, err := ioutil.TempDir("", "scheduler")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store, _ := scheduler.NewFileStore(dir)
+	ctx := context.Background()
+
+	s := scheduler.New(client, store)
+	past := time.Now().Add(-time.Minute)
+	_, _ = s.Schedule(ctx, past.Add(time.Second), reminder("second"))
+	_, _ = s.Schedule(ctx, past, reminder("first"))
+	cancelled, _ := s.Schedule(ctx, past, reminder("cancelled"))
+	later, _ := s.Schedule(ctx, time.Now().Add(time.Hour), reminder("tomorrow"))
+	if err := s.Cancel(ctx, cancelled); err != nil {
+		t.Errorf("Cancel returned an error = %+v; expected no error", err)
+	}
+
+	// A new scheduler over the same directory, as after a restart.
+	store, _ = scheduler.NewFileStore(dir)
+	n, err := scheduler.New(client, store).RunDue(ctx)
+	if err != nil {
+		t.Fatalf("RunDue returned an error = %+v; expected no error", err)
+	}
+
+	if n != 2 || !cmp.Equal(sent.Bodies(), []string{"first", "second"}) {
+		t.Errorf("RunDue() sent %d: %v; want [first second]", n, sent.Bodies())
+	}
+	if _, err := store.Get(ctx, later); err != nil {
+		t.Errorf("Get(later) returned an error = %+v; expected it to still be scheduled", err)
+	}
+}
+
+func TestScheduler_RetriesThenDrops(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	status := http.StatusServiceUnavailable
+	mux.HandleFunc(kik.SendMessageUrl, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	})
+
+	var dropped []*scheduler.Job
+	store := scheduler.NewMemoryStore()
+	s := scheduler.New(client, store)
+	s.Retry = kik.RetryPolicy{MaxAttempts: 3, BaseDelay: time.Nanosecond}
+	s.OnFailure = func(j *scheduler.Job, err error) { dropped = append(dropped, j) }
+	ctx := context.Background()
+
+	id, _ := s.Schedule(ctx, time.Now(), reminder("retry me"))
+	_, _ = s.RunDue(ctx)
+	j, err := store.Get(ctx, id)
+	if err != nil || j.Attempts != 1 {
+		t.Fatalf("after one failure Get() = %v, %v; want a job with 1 attempt", j, err)
+	}
+
+	status = http.StatusBadRequest
+	_, _ = s.RunDue(ctx)
+	if len(dropped) != 1 || dropped[0].Id != id {
+		t.Errorf("OnFailure called with %v; want job %s dropped after a 400", dropped, id)
+	}
+	if _, err := store.Get(ctx, id); err != scheduler.NotFoundError {
+		t.Errorf("Get() of a dropped job = %v; want %v", err, scheduler.NotFoundError)
+	}
+}
+
+func TestScheduler_CancelDuringFailedSendStaysCancelled(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	store := scheduler.NewMemoryStore()
+	s := scheduler.New(client, store)
+	ctx := context.Background()
+	id, _ := s.Schedule(ctx, time.Now(), reminder("cancel me"))
+
+	// The job is cancelled while its send is in flight, then the send fails.
+	mux.HandleFunc(kik.SendMessageUrl, func(w http.ResponseWriter, r *http.Request) {
+		_ = s.Cancel(ctx, id)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if _, err := s.RunDue(ctx); err != nil {
+		t.Fatalf("RunDue returned an error = %+v; expected no error", err)
+	}
+
+	if _, err := store.Get(ctx, id); err != scheduler.NotFoundError {
+		t.Errorf("Get() of a cancelled job = %v; want %v", err, scheduler.NotFoundError)
+	}
+}
+
+func TestScheduler_CancelInterruptsSend(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	store := scheduler.NewMemoryStore()
+	s := scheduler.New(client, store)
+	id, _ := s.Schedule(context.Background(), time.Now(), reminder("hanging"))
+
+	// Kik doesn't answer until the test is over, only cancelling the context ends the send.
+	release := make(chan struct{})
+	defer close(release)
+	mux.HandleFunc(kik.SendMessageUrl, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	if sent, err := s.RunDue(ctx); sent != 0 || err != nil {
+		t.Fatalf("RunDue() = %d, %v; want 0, nil", sent, err)
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("RunDue() returned after %s; want it to stop when the context is done", d)
+	}
+
+	// The interrupted send isn't an attempt, the job is sent on the next run.
+	j, err := store.Get(context.Background(), id)
+	if err != nil || j.Attempts != 0 {
+		t.Errorf("Get() = %+v, %v; want the job with no attempts", j, err)
+	}
+}
@@ -0,0 +1,188 @@
+package scheduler
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/4kelly/go-kik/kik/internal/filestore"
+)
+
+// MemoryStore keeps jobs in memory, they are lost on restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	jobs map[string][]byte // Encoded, so callers never share a *Job with the store.
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{jobs: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Put(ctx context.Context, j *Job) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[j.Id] = b
+	return nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, j *Job) error {
+	b, err := json.Marshal(j)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[j.Id]; !ok {
+		return NotFoundError
+	}
+	s.jobs[j.Id] = b
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	b, ok := s.jobs[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, NotFoundError
+	}
+	return decodeJob(b)
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.jobs[id]; !ok {
+		return NotFoundError
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *MemoryStore) Due(ctx context.Context, now time.Time, limit int) ([]*Job, error) {
+	s.mu.Lock()
+	encoded := make([][]byte, 0, len(s.jobs))
+	for _, b := range s.jobs {
+		encoded = append(encoded, b)
+	}
+	s.mu.Unlock()
+	return due(encoded, now, limit)
+}
+
+// FileStore keeps one JSON file per job in a directory, so scheduled jobs survive restarts.
+type FileStore struct {
+	Dir string
+
+	mu sync.Mutex
+}
+
+// NewFileStore returns a FileStore writing to dir, creating it if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir}, nil
+}
+
+func (s *FileStore) Put(ctx context.Context, j *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.write(j)
+}
+
+func (s *FileStore) Update(ctx context.Context, j *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := os.Stat(s.path(j.Id)); os.IsNotExist(err) {
+		return NotFoundError
+	}
+	return s.write(j)
+}
+
+func (s *FileStore) write(j *Job) error {
+	return filestore.WriteJSON(s.path(j.Id), j)
+}
+
+func (s *FileStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	b, err := ioutil.ReadFile(s.path(id))
+	if os.IsNotExist(err) {
+		return nil, NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+	return decodeJob(b)
+}
+
+func (s *FileStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(id))
+	if os.IsNotExist(err) {
+		return NotFoundError
+	}
+	return err
+}
+
+func (s *FileStore) Due(ctx context.Context, now time.Time, limit int) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	encoded, err := filestore.ReadAll(s.Dir)
+	if err != nil {
+		return nil, err
+	}
+	return due(encoded, now, limit)
+}
+
+// path only accepts ids made by newId, anything else could escape Dir.
+func (s *FileStore) path(id string) string {
+	id = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || (r >= 'a' && r <= 'f') {
+			return r
+		}
+		return '_'
+	}, id)
+	return filepath.Join(s.Dir, id+".json")
+}
+
+func decodeJob(b []byte) (*Job, error) {
+	var j Job
+	if err := json.Unmarshal(b, &j); err != nil {
+		return nil, err
+	}
+	return &j, nil
+}
+
+// due decodes the jobs due at now, earliest first.
+func due(encoded [][]byte, now time.Time, limit int) ([]*Job, error) {
+	records, err := filestore.Due(encoded, now, limit, func(b []byte) (filestore.Record, error) {
+		j, err := decodeJob(b)
+		if err != nil {
+			return filestore.Record{}, err
+		}
+		return filestore.Record{Value: j, DueAt: j.RunAt, Order: j.RunAt}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]*Job, len(records))
+	for i, r := range records {
+		jobs[i] = r.Value.(*Job)
+	}
+	return jobs, nil
+}
@@ -0,0 +1,155 @@
+// Package session stores per-conversation state for bots built on the kik package.
+//
+// Wrap a kik.Handler with Wrap and the session for the conversation is loaded before each
+// message is handled and saved afterwards. Handlers read and write it with FromContext.
+// Sessions are persisted through a Store: MemoryStore and FileStore are included,
+// anything else (Redis, SQL, ...) only has to implement the three Store methods.
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// NotFoundError is returned by a Store when there is no session for a key.
+var NotFoundError = errors.New("session not found")
+
+// Store persists encoded sessions. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the data saved for key, or NotFoundError.
+	Load(ctx context.Context, key string) ([]byte, error)
+	Save(ctx context.Context, key string, data []byte) error
+	Delete(ctx context.Context, key string) error
+}
+
+// KeyFunc picks the session key for a message.
+type KeyFunc func(m kik.Receive) string
+
+// ByChat keys sessions by ChatId, all participants of a group share one session.
+func ByChat(m kik.Receive) string {
+	return m.Common().ChatId
+}
+
+// ByChatAndUser keys sessions by ChatId and From, every participant of a group has their own session.
+func ByChatAndUser(m kik.Receive) string {
+	c := m.Common()
+	return c.ChatId + "/" + c.From
+}
+
+// Session is the state of one conversation.
+type Session struct {
+	Key       string            `json:"key"`
+	Values    map[string]string `json:"values"`
+	UpdatedAt time.Time         `json:"updatedAt"`
+
+	changed bool
+	cleared bool
+}
+
+// Get returns the value for name, or "" if it is not set.
+func (s *Session) Get(name string) string {
+	return s.Values[name]
+}
+
+// Set stores a value in the session.
+func (s *Session) Set(name, value string) {
+	if s.Values == nil {
+		s.Values = map[string]string{}
+	}
+	s.Values[name] = value
+	s.changed = true
+}
+
+// Delete removes a single value from the session.
+func (s *Session) Delete(name string) {
+	if _, ok := s.Values[name]; ok {
+		delete(s.Values, name)
+		s.changed = true
+	}
+}
+
+// Clear removes every value, the session is deleted from the store once the message has been handled.
+func (s *Session) Clear() {
+	s.Values = map[string]string{}
+	s.cleared = true
+	s.changed = true
+}
+
+type contextKey struct{}
+
+// FromContext returns the session of the message being handled, or nil outside of Wrap.
+func FromContext(ctx context.Context) *Session {
+	s, _ := ctx.Value(contextKey{}).(*Session)
+	return s
+}
+
+// NewContext returns a copy of ctx carrying s, useful for testing handlers without a Store.
+func NewContext(ctx context.Context, s *Session) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// Wrap returns a Handler that loads the session chosen by key before calling next,
+// and saves it afterwards if next changed it. A nil key defaults to ByChat.
+// The session is not saved when next returns an error, so a redelivered message sees the old state.
+func Wrap(store Store, key KeyFunc, next kik.Handler) kik.Handler {
+	if key == nil {
+		key = ByChat
+	}
+	return kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		s, err := Load(ctx, store, key(m))
+		if err != nil {
+			return err
+		}
+
+		if err := next.HandleMessage(NewContext(ctx, s), c, m); err != nil {
+			return err
+		}
+		return Save(ctx, store, s)
+	})
+}
+
+// Load reads a session from store, returning a new empty session if there is none.
+func Load(ctx context.Context, store Store, key string) (*Session, error) {
+	data, err := store.Load(ctx, key)
+	if errors.Is(err, NotFoundError) {
+		return &Session{Key: key, Values: map[string]string{}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var s Session
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	s.Key = key
+	if s.Values == nil {
+		s.Values = map[string]string{}
+	}
+	return &s, nil
+}
+
+// Save writes a session to store if it changed since it was loaded. Cleared sessions are deleted.
+func Save(ctx context.Context, store Store, s *Session) error {
+	if !s.changed {
+		return nil
+	}
+	if s.cleared && len(s.Values) == 0 {
+		return store.Delete(ctx, s.Key)
+	}
+
+	s.UpdatedAt = time.Now()
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := store.Save(ctx, s.Key, data); err != nil {
+		return err
+	}
+	s.changed, s.cleared = false, false
+	return nil
+}
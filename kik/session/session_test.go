@@ -0,0 +1,97 @@
+package session_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kik/session"
+)
+
+func textFrom(chatId, from, body string) kik.Receive {
+	return &kik.TextMessageReceive{
+		ReceiveMessage: kik.ReceiveMessage{ChatId: chatId, From: from, Type: "text"},
+		Body:           body,
+	}
+}
+
+// countingHandler counts the messages seen per session and records the latest count.
+func countingHandler(last *string) kik.Handler {
+	return kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		s := session.FromContext(ctx)
+		s.Set("count", s.Get("count")+"x")
+		*last = s.Get("count")
+		return nil
+	})
+}
+
+func TestWrap_PersistsStatePerChat(t *testing.T) {
+	dir, err := ioutil.TempDir("", "session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fileStore, err := session.NewFileStore(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stores := map[string]session.Store{
+		"memory": session.NewMemoryStore(time.Hour),
+		"file":   fileStore,
+	}
+	for name, store := range stores {
+		var last string
+		h := session.Wrap(store, session.ByChatAndUser, countingHandler(&last))
+		ctx := context.Background()
+
+		_ = h.HandleMessage(ctx, nil, textFrom("c1", "alice", "one"))
+		_ = h.HandleMessage(ctx, nil, textFrom("c1", "bob", "one"))
+		_ = h.HandleMessage(ctx, nil, textFrom("c1", "alice", "two"))
+
+		if last != "xx" {
+			t.Errorf("%s: count for c1/alice = %q; want %q", name, last, "xx")
+		}
+	}
+}
+
+func TestMemoryStore_ExpiresAfterTTL(t *testing.T) {
+	store := session.NewMemoryStore(10 * time.Millisecond)
+	ctx := context.Background()
+
+	if err := store.Save(ctx, "c1", []byte(`{}`)); err != nil {
+		t.Fatalf("Save returned an error = %+v; expected no error", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+
+	if _, err := store.Load(ctx, "c1"); err != session.NotFoundError {
+		t.Errorf("Load() after TTL returned %v; want %v", err, session.NotFoundError)
+	}
+	store.Sweep()
+	if store.Len() != 0 {
+		t.Errorf("Len() after Sweep = %d; want 0", store.Len())
+	}
+}
+
+func TestSave_ClearDeletesSession(t *testing.T) {
+	store := session.NewMemoryStore(0)
+	ctx := context.Background()
+
+	s, _ := session.Load(ctx, store, "c1")
+	s.Set("step", "2")
+	_ = session.Save(ctx, store, s)
+
+	s, _ = session.Load(ctx, store, "c1")
+	if s.Get("step") != "2" {
+		t.Errorf("Get(step) = %q; want %q", s.Get("step"), "2")
+	}
+	s.Clear()
+	_ = session.Save(ctx, store, s)
+
+	if _, err := store.Load(ctx, "c1"); err != session.NotFoundError {
+		t.Errorf("Load() after Clear returned %v; want %v", err, session.NotFoundError)
+	}
+}
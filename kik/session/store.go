@@ -0,0 +1,171 @@
+package session
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps sessions in memory and evicts them once they have not been saved for the TTL.
+// Expired sessions are never returned, and are removed from memory as new sessions are saved.
+type MemoryStore struct {
+	TTL time.Duration // Zero means sessions never expire.
+
+	mu        sync.Mutex
+	items     map[string]memoryItem
+	lastSweep time.Time
+}
+
+type memoryItem struct {
+	data    []byte
+	expires time.Time
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{TTL: ttl, items: map[string]memoryItem{}}
+}
+
+func (s *MemoryStore) Load(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item, ok := s.items[key]
+	if !ok || s.expired(item) {
+		return nil, NotFoundError
+	}
+	return item.data, nil
+}
+
+func (s *MemoryStore) Save(ctx context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.items == nil {
+		s.items = map[string]memoryItem{}
+	}
+
+	now := time.Now()
+	var expires time.Time
+	if s.TTL > 0 {
+		expires = now.Add(s.TTL)
+		if now.Sub(s.lastSweep) > s.TTL {
+			s.sweep()
+			s.lastSweep = now
+		}
+	}
+	s.items[key] = memoryItem{data: data, expires: expires}
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.items, key)
+	return nil
+}
+
+// Len returns the number of sessions held in memory, including expired ones not yet evicted.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.items)
+}
+
+// Sweep evicts every expired session.
+func (s *MemoryStore) Sweep() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sweep()
+}
+
+func (s *MemoryStore) sweep() {
+	for k, item := range s.items {
+		if s.expired(item) {
+			delete(s.items, k)
+		}
+	}
+}
+
+func (s *MemoryStore) expired(item memoryItem) bool {
+	return !item.expires.IsZero() && !time.Now().Before(item.expires)
+}
+
+// FileStore keeps one file per session in a directory, so sessions survive restarts of a single instance.
+// Sessions that have not been saved for the TTL are treated as missing and removed when loaded.
+type FileStore struct {
+	Dir string
+	TTL time.Duration // Zero means sessions never expire.
+
+	mu sync.Mutex
+}
+
+// NewFileStore returns a FileStore writing to dir, creating it if needed.
+func NewFileStore(dir string, ttl time.Duration) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+	return &FileStore{Dir: dir, TTL: ttl}, nil
+}
+
+func (s *FileStore) Load(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(key)
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, NotFoundError
+	}
+	if err != nil {
+		return nil, err
+	}
+	if s.TTL > 0 && time.Since(info.ModTime()) >= s.TTL {
+		_ = os.Remove(path)
+		return nil, NotFoundError
+	}
+	return ioutil.ReadFile(path)
+}
+
+func (s *FileStore) Save(ctx context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Write to a temporary file first so a crash never leaves a half written session.
+	tmp, err := ioutil.TempFile(s.Dir, ".session-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+func (s *FileStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := os.Remove(s.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+// path hashes the key, chat ids and usernames are not safe to use as file names.
+func (s *FileStore) path(key string) string {
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(s.Dir, hex.EncodeToString(sum[:])+".json")
+}
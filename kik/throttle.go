@@ -0,0 +1,144 @@
+package kik
+
+import (
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Limit is a rate of at most Messages in any period of Per. A zero Limit is unlimited.
+type Limit struct {
+	Messages int
+	Per      time.Duration
+}
+
+// Flood is a message Throttle dropped because its sender or chat went over their Limit.
+type Flood struct {
+	Message Receive
+	Chat    bool // Whether the chat's limit was hit rather than the sender's.
+
+	// ReplyErr is why the SlowDownReply for the message couldn't be sent, if one was sent.
+	ReplyErr error
+}
+
+// Throttle protects a bot from users and chats that send too many messages, and from blocked users.
+// Dropped messages are acknowledged to Kik, so they aren't delivered again.
+// Use Throttle.Wrap as Middleware, before anything expensive runs.
+type Throttle struct {
+	PerUser Limit // Limit for each sender.
+	PerChat Limit // Limit for each chat, shared by everyone in a group.
+
+	// SlowDownReply is sent to a sender when their messages start being dropped,
+	// at most once per PerUser.Per, or once a minute for chat limits.
+	SlowDownReply string
+	// OnFlood is called for every dropped message that went over a limit, after any SlowDownReply was sent.
+	// When nil, failures to send the SlowDownReply are logged.
+	OnFlood func(ctx context.Context, f Flood)
+
+	mu        sync.RWMutex
+	blocked   map[string]bool
+	allowed   map[string]bool
+	users     *limiter
+	chats     *limiter
+	slowDowns *limiter
+	once      sync.Once
+}
+
+// NewThrottle returns a Throttle with the given limits.
+func NewThrottle(perUser, perChat Limit) *Throttle {
+	return &Throttle{PerUser: perUser, PerChat: perChat}
+}
+
+// Block drops every message from usernames until they are unblocked.
+func (t *Throttle) Block(usernames ...string) {
+	t.set(&t.blocked, usernames, true)
+}
+
+// Unblock undoes Block.
+func (t *Throttle) Unblock(usernames ...string) {
+	t.set(&t.blocked, usernames, false)
+}
+
+// Allow exempts usernames from the limits, such as the bot's admins. It doesn't undo Block.
+func (t *Throttle) Allow(usernames ...string) {
+	t.set(&t.allowed, usernames, true)
+}
+
+func (t *Throttle) set(list *map[string]bool, usernames []string, on bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if *list == nil {
+		*list = map[string]bool{}
+	}
+	for _, u := range usernames {
+		if on {
+			(*list)[strings.ToLower(u)] = true
+		} else {
+			delete(*list, strings.ToLower(u))
+		}
+	}
+}
+
+func (t *Throttle) init() {
+	t.once.Do(func() {
+		if t.PerUser.Messages > 0 {
+			t.users = newLimiter(t.PerUser.Messages, t.PerUser.Per)
+		}
+		if t.PerChat.Messages > 0 {
+			t.chats = newLimiter(t.PerChat.Messages, t.PerChat.Per)
+		}
+		per := t.PerUser.Per
+		if t.users == nil || per <= 0 {
+			per = time.Minute
+		}
+		t.slowDowns = newLimiter(1, per)
+	})
+}
+
+// Wrap returns a Handler that only passes on the messages within the limits.
+func (t *Throttle) Wrap(next Handler) Handler {
+	t.init()
+	return HandlerFunc(func(ctx context.Context, c *Client, m Receive) error {
+		common := m.Common()
+		from := strings.ToLower(common.From)
+
+		t.mu.RLock()
+		blocked, allowed := t.blocked[from], t.allowed[from]
+		t.mu.RUnlock()
+		if blocked {
+			return nil
+		}
+		if allowed {
+			return next.HandleMessage(ctx, c, m)
+		}
+
+		now := time.Now()
+		var flood *Flood
+		switch {
+		case t.users != nil && from != "" && !t.users.allow(from, now):
+			flood = &Flood{Message: m}
+		case t.chats != nil && common.ChatId != "" && !t.chats.allow(common.ChatId, now):
+			flood = &Flood{Message: m, Chat: true}
+		}
+		if flood == nil {
+			return next.HandleMessage(ctx, c, m)
+		}
+
+		// A failed reply doesn't fail the delivery, Kik would redeliver the message the throttle dropped.
+		if t.SlowDownReply != "" && from != "" && t.slowDowns.allow(from, now) {
+			flood.ReplyErr = c.SendMessageContext(ctx, []Message{TextMessage{
+				SendMessage: SendMessage{To: common.From, ChatId: common.ChatId, Type: "text"},
+				Body:        t.SlowDownReply,
+			}})
+		}
+		switch {
+		case t.OnFlood != nil:
+			t.OnFlood(ctx, *flood)
+		case flood.ReplyErr != nil:
+			log.Printf("could not tell %s to slow down: %v", common.From, flood.ReplyErr)
+		}
+		return nil
+	})
+}
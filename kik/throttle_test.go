@@ -0,0 +1,95 @@
+package kik_test
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+func TestThrottle_LimitsUsersAndChats(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	rec := kiktest.RecordMessages(mux)
+
+	throttle := kik.NewThrottle(kik.Limit{Messages: 2, Per: time.Hour}, kik.Limit{Messages: 3, Per: time.Hour})
+	throttle.SlowDownReply = "Slow down!"
+	throttle.Block("spammer")
+	throttle.Allow("admin")
+	var floods []string
+	throttle.OnFlood = func(ctx context.Context, f kik.Flood) {
+		common := f.Message.Common()
+		if f.Chat {
+			floods = append(floods, "chat "+common.ChatId)
+		} else {
+			floods = append(floods, "user "+common.From)
+		}
+	}
+
+	var handled []string
+	h := kik.Chain(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		handled = append(handled, m.(*kik.TextMessageReceive).Body)
+		return nil
+	}), throttle.Wrap)
+
+	inGroup := func(from, body string) kik.Receive {
+		m := textFrom(from, body)
+		m.(*kik.TextMessageReceive).ChatId = "group"
+		return m
+	}
+	for _, m := range []kik.Receive{
+		textFrom("alice", "1"), textFrom("alice", "2"), textFrom("alice", "3"), textFrom("alice", "4"),
+		textFrom("spammer", "5"),
+		inGroup("bob", "6"), inGroup("carol", "7"), inGroup("dave", "8"), inGroup("erin", "9"),
+		textFrom("admin", "10"), textFrom("admin", "11"), textFrom("admin", "12"),
+	} {
+		if err := h.HandleMessage(context.Background(), client, m); err != nil {
+			t.Fatalf("HandleMessage() error = %v", err)
+		}
+	}
+
+	if got := strings.Join(handled, ","); got != "1,2,6,7,8,10,11,12" {
+		t.Errorf("handled %s; want 1,2,6,7,8,10,11,12", got)
+	}
+	if got := strings.Join(floods, ","); got != "user alice,user alice,chat group" {
+		t.Errorf("floods %s; want user alice twice, then chat group", got)
+	}
+
+	// alice is told to slow down once, erin once for the group.
+	sent := rec.Messages()
+	if len(sent) != 2 || sent[0]["to"] != "alice" || sent[1]["to"] != "erin" || sent[0]["body"] != "Slow down!" {
+		t.Errorf("sent %v; want one slow down reply to alice and one to erin", sent)
+	}
+}
+
+func TestThrottle_FailedSlowDownReplyIsReported(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+	mux.HandleFunc(kik.SendMessageUrl, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+
+	throttle := kik.NewThrottle(kik.Limit{Messages: 1, Per: time.Hour}, kik.Limit{})
+	throttle.SlowDownReply = "Slow down!"
+	var replyErrs []error
+	throttle.OnFlood = func(ctx context.Context, f kik.Flood) {
+		replyErrs = append(replyErrs, f.ReplyErr)
+	}
+	h := throttle.Wrap(kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		return nil
+	}))
+
+	// The dropped message is acknowledged even though the reply failed, so Kik doesn't redeliver it.
+	for _, body := range []string{"1", "2"} {
+		if err := h.HandleMessage(context.Background(), client, textFrom("alice", body)); err != nil {
+			t.Fatalf("HandleMessage(%s) error = %v; want nil", body, err)
+		}
+	}
+	if len(replyErrs) != 1 || replyErrs[0] == nil {
+		t.Errorf("OnFlood reply errors = %v; want the failed slow down reply", replyErrs)
+	}
+}
@@ -3,6 +3,8 @@ package kik
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 )
 
 // User is the response body of a User profile from the Kik bot API.
@@ -73,14 +75,63 @@ type Messages struct {
 	Messages []Message `json:"messages"`
 }
 
+// UnmarshalJSON decodes each message into the send type matching its "type" field,
+// so messages persisted as JSON can be sent again.
+// It returns NotMessageTypeError for types that can't be sent.
+func (v *Messages) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Messages []json.RawMessage `json:"messages"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	v.Messages = make([]Message, 0, len(raw.Messages))
+	for _, r := range raw.Messages {
+		var header struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(r, &header); err != nil {
+			return err
+		}
+
+		var actual Message
+		switch header.Type {
+		case "text":
+			actual = &TextMessage{}
+		case "picture":
+			actual = &PictureMessage{}
+		case "link":
+			actual = &LinkMessage{}
+		case "video":
+			actual = &VideoMessage{}
+		case "read-receipt":
+			actual = &ReadReceiptMessage{}
+		default:
+			return fmt.Errorf("%w: %q", NotMessageTypeError, header.Type)
+		}
+
+		if err := json.Unmarshal(r, actual); err != nil {
+			return err
+		}
+		v.Messages = append(v.Messages, actual)
+	}
+	return nil
+}
+
 // Message is a dummy interface so that all structs that embedd `Message` share a common interface.
 type Message interface {
 	message()
+	// Common returns the fields shared by every message type that can be sent.
+	Common() SendMessage
 }
 
 // Implement the dummy interface
 func (t SendMessage) message() { return }
 
+// Common returns the fields shared by every message type that can be sent.
+func (t SendMessage) Common() SendMessage { return t }
+
 type SendMessage struct {
 	To        string                      `json:"to"`                  // The user or group that will receive the message
 	Type      string                      `json:"type"`                // The type of message. See Message Types for the values you can see in this field.
@@ -124,6 +175,17 @@ func (v *ReceivedMessages) UnmarshalJSON(data []byte) error {
 			actual = &TextMessageReceive{}
 		case "picture":
 			actual = &PictureMessageReceive{}
+		case "link":
+			actual = &LinkMessageReceive{}
+		case "video":
+			actual = &VideoMessageReceive{}
+		case "scan-data":
+			actual = &ScanDataReceive{}
+		case "start-chatting":
+			actual = &StartChattingReceive{}
+		default:
+			// Unknown types still carry the common fields, so don't drop them.
+			actual = &ReceiveMessage{}
 		}
 
 		err = json.Unmarshal(r, actual)
@@ -138,11 +200,16 @@ func (v *ReceivedMessages) UnmarshalJSON(data []byte) error {
 // Receive is a dummy interface so that all structs that embedd `Receive` share a common interface.
 type Receive interface {
 	receive()
+	// Common returns the fields shared by every received message type.
+	Common() ReceiveMessage
 }
 
 // Implements dummy interface.
 func (t ReceiveMessage) receive() { return }
 
+// Common returns the fields shared by every received message type.
+func (t ReceiveMessage) Common() ReceiveMessage { return t }
+
 type ReceiveMessage struct {
 	ChatId               string   `json:"chatId"`       // The identifier for the conversation your bot is involved in. This field is recommended for all responses in order for messages to be routed correctly (for example, if you're messaging a user in a group)
 	Id                   string   `json:"id"`           // randomUUID() ID for this message.Use this to link messages to receipts.This will always be present for received messages.
@@ -217,12 +284,29 @@ type VideoMessage struct {
 	Attribution *Attribution `json:"attribution,omitempty"`
 }
 
+// ReadReceiptMessage tells the sender that the bot has read their messages.
+type ReadReceiptMessage struct {
+	SendMessage
+	MessageIds []string `json:"messageIds"` // The ids of the messages that were read.
+}
+
 type VideoMessageReceive struct {
 	ReceiveMessage
 	VideoUrl    string       `json:"videoUrl"` // The URL of the video or GIF you wish to send.
 	Attribution *Attribution `json:"attribution,omitempty"`
 }
 
+// ScanDataReceive is sent to the bot when a user scans a Kik Code that was created with ScanData.
+type ScanDataReceive struct {
+	ReceiveMessage
+	Data string `json:"data"` // The data embedded in the scanned Kik Code.
+}
+
+// StartChattingReceive is sent to the bot when a user opens a chat with it for the first time.
+type StartChattingReceive struct {
+	ReceiveMessage
+}
+
 /*
 Configuration
 */
@@ -265,3 +349,28 @@ Error Types
 
 var NotMessageTypeError = errors.New("not a valid message type")
 var HttpError = errors.New("HTTP request did not return 200")
+var InvalidSignatureError = errors.New("invalid webhook signature")
+var UnknownBotError = errors.New("no bot registered for this username")
+var NotScanPayloadError = errors.New("scan data was not encoded with EncodeScanData")
+
+// ApiError is returned when the Kik API responds with anything but a 200. It wraps HttpError.
+type ApiError struct {
+	Method     string
+	Url        string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *ApiError) Error() string {
+	return fmt.Sprintf("%v: %s %s returned: <%v> %s", HttpError, e.Method, e.Url, e.StatusCode, e.Body)
+}
+
+func (e *ApiError) Unwrap() error {
+	return HttpError
+}
+
+// IsNotFound reports whether err is an ApiError for a 404, such as GetUser for an unknown user.
+func IsNotFound(err error) bool {
+	var apiErr *ApiError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound
+}
@@ -0,0 +1,200 @@
+package kik
+
+import (
+	"container/list"
+	"context"
+	"strings"
+	"sync"
+	"time"
+)
+
+// UserCache caches GetUser lookups, bots tend to look up the same sender on every message.
+//
+// Entries expire after the TTL and the least recently used entry is evicted once the cache is full.
+// Concurrent lookups of the same user share a single request, and unknown users (404s) are cached
+// too so they aren't looked up again on every message. Usernames are case-insensitive.
+type UserCache struct {
+	client *Client
+	ttl    time.Duration
+	size   int
+
+	// NegativeTTL is how long a 404 is cached for, defaults to the TTL.
+	NegativeTTL time.Duration
+
+	mu       sync.Mutex
+	entries  map[string]*list.Element
+	lru      *list.List // Front is the most recently used *userEntry.
+	inflight map[string]*userCall
+}
+
+type userEntry struct {
+	username string
+	user     *User
+	err      error // Only ever a 404.
+	expires  time.Time
+
+	picture         []byte
+	pictureModified int64 // ProfilePicLastModified the picture was downloaded for.
+}
+
+// userCall is a lookup in flight, shared by everyone asking for the same user.
+type userCall struct {
+	done chan struct{}
+	user *User
+	err  error
+}
+
+// NewUserCache returns an empty cache of at most size users backed by client.
+func NewUserCache(client *Client, ttl time.Duration, size int) *UserCache {
+	if size < 1 {
+		size = 1
+	}
+	return &UserCache{
+		client:   client,
+		ttl:      ttl,
+		size:     size,
+		entries:  map[string]*list.Element{},
+		lru:      list.New(),
+		inflight: map[string]*userCall{},
+	}
+}
+
+// GetUser returns a user's profile from the cache, looking it up with the Client if needed.
+// The returned User is a copy, callers may modify it.
+func (c *UserCache) GetUser(ctx context.Context, username string) (*User, error) {
+	e, err := c.entry(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+	user := *e.user
+	return &user, nil
+}
+
+// ProfilePicture returns the encoded profile picture of a user as downloaded from ProfilePicUrl.
+// The picture is only downloaded again once ProfilePicLastModified changes.
+func (c *UserCache) ProfilePicture(ctx context.Context, username string) ([]byte, error) {
+	e, err := c.entry(ctx, username)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	picture, modified := e.picture, e.pictureModified
+	c.mu.Unlock()
+	if picture != nil && modified == e.user.ProfilePicLastModified {
+		return picture, nil
+	}
+
+	picture, err = c.client.downloadPicture(ctx, e.user.ProfilePicUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	e.picture, e.pictureModified = picture, e.user.ProfilePicLastModified
+	c.mu.Unlock()
+	return picture, nil
+}
+
+// Invalidate drops a user from the cache.
+func (c *UserCache) Invalidate(username string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.entries[strings.ToLower(username)]; ok {
+		c.lru.Remove(el)
+		delete(c.entries, strings.ToLower(username))
+	}
+}
+
+// Len returns the number of cached users, including unknown ones.
+func (c *UserCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// entry returns a fresh entry for username, looking it up if it is missing or expired.
+func (c *UserCache) entry(ctx context.Context, username string) (*userEntry, error) {
+	key := strings.ToLower(username)
+
+	c.mu.Lock()
+	var stale *userEntry
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*userEntry)
+		if time.Now().Before(e.expires) {
+			c.lru.MoveToFront(el)
+			c.mu.Unlock()
+			if e.err != nil {
+				return nil, e.err
+			}
+			return e, nil
+		}
+		stale = e
+	}
+
+	call, ok := c.inflight[key]
+	if !ok {
+		call = &userCall{done: make(chan struct{})}
+		c.inflight[key] = call
+		go c.lookup(key, username, stale, call)
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		return el.Value.(*userEntry), nil
+	}
+	// Evicted already by a busy cache, hand back what was fetched.
+	return &userEntry{username: key, user: call.user}, nil
+}
+
+// lookup fetches a user and stores the result. It runs detached from any caller's context,
+// so a caller giving up doesn't fail the lookup for everyone else waiting on it.
+func (c *UserCache) lookup(key, username string, stale *userEntry, call *userCall) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	call.user, call.err = c.client.getUser(ctx, username)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.inflight, key)
+	defer close(call.done)
+
+	e := &userEntry{username: key, user: call.user, err: call.err, expires: time.Now().Add(c.ttl)}
+	switch {
+	case call.err != nil && !IsNotFound(call.err):
+		// Don't cache transient failures.
+		return
+	case call.err != nil:
+		ttl := c.NegativeTTL
+		if ttl == 0 {
+			ttl = c.ttl
+		}
+		e.expires = time.Now().Add(ttl)
+	case stale != nil && stale.user != nil && stale.pictureModified == call.user.ProfilePicLastModified:
+		e.picture, e.pictureModified = stale.picture, stale.pictureModified
+	}
+
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.lru.MoveToFront(el)
+		return
+	}
+	c.entries[key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*userEntry).username)
+	}
+}
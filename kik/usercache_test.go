@@ -0,0 +1,114 @@
+package kik_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+func TestUserCache_SharesConcurrentLookups(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var lookups int32
+	mux.HandleFunc(kik.GetUserUrl, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&lookups, 1)
+		time.Sleep(20 * time.Millisecond)
+		fmt.Fprint(w, `{"firstName": "Ryan"}`)
+	})
+
+	cache := kik.NewUserCache(client, time.Hour, 10)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := cache.GetUser(context.Background(), username)
+			if err != nil || user.FirstName != "Ryan" {
+				t.Errorf("GetUser(%s) = %v, %v; want Ryan", username, user, err)
+			}
+		}()
+	}
+	wg.Wait()
+	_, _ = cache.GetUser(context.Background(), strings.ToUpper(username))
+
+	if lookups != 1 {
+		t.Errorf("GetUser made %d lookups; want 1", lookups)
+	}
+}
+
+func TestUserCache_CachesNotFoundAndEvicts(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	lookups := map[string]int{}
+	mux.HandleFunc(kik.GetUserUrl, func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, kik.GetUserUrl)
+		lookups[name]++
+		if name == "ghost" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"firstName": "Someone"}`)
+	})
+
+	cache := kik.NewUserCache(client, time.Hour, 2)
+	ctx := context.Background()
+	for _, name := range []string{"ghost", "ghost", "a", "b", "a", "c", "b"} {
+		_, err := cache.GetUser(ctx, name)
+		if name == "ghost" && !kik.IsNotFound(err) {
+			t.Errorf("GetUser(ghost) = %v; want a 404", err)
+		}
+	}
+
+	// ghost is cached as missing, then evicted by a and b. c evicts b, the least recently used.
+	want := map[string]int{"ghost": 1, "a": 1, "b": 2, "c": 1}
+	for name, n := range want {
+		if lookups[name] != n {
+			t.Errorf("lookups of %s = %d; want %d", name, lookups[name], n)
+		}
+	}
+	if cache.Len() != 2 {
+		t.Errorf("Len() = %d; want 2", cache.Len())
+	}
+}
+
+func TestUserCache_RefreshesPictureOnlyWhenModified(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var modified int32 = 1
+	mux.HandleFunc(kik.GetUserUrl, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"profilePicUrl": "%s/pic", "profilePicLastModified": %d}`,
+			strings.TrimSuffix(client.BaseUrl.String(), "/"), atomic.LoadInt32(&modified))
+	})
+	downloads := 0
+	mux.HandleFunc("/pic", func(w http.ResponseWriter, r *http.Request) {
+		downloads++
+		w.Write(pngBytes(t, 1, 1))
+	})
+
+	cache := kik.NewUserCache(client, time.Millisecond, 10)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			atomic.StoreInt32(&modified, 2)
+		}
+		time.Sleep(2 * time.Millisecond)
+		if _, err := cache.ProfilePicture(ctx, username); err != nil {
+			t.Fatalf("ProfilePicture returned an error = %+v; expected no error", err)
+		}
+	}
+
+	if downloads != 2 {
+		t.Errorf("ProfilePicture downloaded %d times; want 2", downloads)
+	}
+}
@@ -0,0 +1,67 @@
+package kik
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UserErrors holds the lookups that failed in GetUsers, keyed by username.
+type UserErrors map[string]error
+
+func (e UserErrors) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msgs := make([]string, 0, len(names))
+	for _, name := range names {
+		msgs = append(msgs, fmt.Sprintf("%s: %v", name, e[name]))
+	}
+	return fmt.Sprintf("%d user lookups failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+// GetUsers looks up many users in parallel, see BatchOptions, for example to enrich the Participants of a group.
+// Every user found is in the returned map. If any lookup failed the error is a UserErrors,
+// or ctx.Err() if ctx was done before every lookup started.
+func (k *Client) GetUsers(ctx context.Context, usernames []string, opts *BatchOptions) (map[string]*User, error) {
+	if opts == nil {
+		opts = &BatchOptions{}
+	}
+
+	var unique []string
+	seen := map[string]bool{}
+	for _, name := range usernames {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	var mu sync.Mutex
+	users := make(map[string]*User, len(unique))
+	errs := UserErrors{}
+	err := opts.forEach(ctx, len(unique), func(ctx context.Context, i int) {
+		user, err := k.getUser(ctx, unique[i])
+
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			errs[unique[i]] = err
+			return
+		}
+		users[unique[i]] = user
+	})
+
+	if err != nil {
+		return users, err
+	}
+	if len(errs) > 0 {
+		return users, errs
+	}
+	return users, nil
+}
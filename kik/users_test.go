@@ -0,0 +1,57 @@
+package kik_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+func TestGetUsers_ReturnsUsersAndPerUserErrors(t *testing.T) {
+	client, mux, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	var mu sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	mux.HandleFunc(kik.GetUserUrl, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		inFlight++
+		if inFlight > maxInFlight {
+			maxInFlight = inFlight
+		}
+		mu.Unlock()
+		defer func() {
+			mu.Lock()
+			inFlight--
+			mu.Unlock()
+		}()
+
+		name := strings.TrimPrefix(r.URL.Path, kik.GetUserUrl)
+		if name == "ghost" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, `{"firstName": "%s"}`, name)
+	})
+
+	names := []string{"a", "b", "ghost", "c", "a", "d"}
+	users, err := client.GetUsers(context.Background(), names, &kik.BatchOptions{Concurrency: 2})
+
+	errs, ok := err.(kik.UserErrors)
+	if !ok || len(errs) != 1 || !kik.IsNotFound(errs["ghost"]) {
+		t.Errorf("GetUsers() error = %v; want a 404 for ghost only", err)
+	}
+	for _, name := range []string{"a", "b", "c", "d"} {
+		if users[name] == nil || users[name].FirstName != name {
+			t.Errorf("GetUsers()[%s] = %v; want a user named %s", name, users[name], name)
+		}
+	}
+	if maxInFlight > 2 {
+		t.Errorf("GetUsers() made %d concurrent lookups; want at most 2", maxInFlight)
+	}
+}
@@ -2,6 +2,7 @@ package kik
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -20,8 +21,7 @@ func (k *Client) do(req *http.Request, v interface{}) error {
 	if resp.StatusCode != http.StatusOK {
 		defer resp.Body.Close()
 		b, _ := ioutil.ReadAll(resp.Body)
-		return fmt.Errorf("%v: %s %s %s returned: <%v> %s",
-			HttpError, req.Method, req.URL, req.Body, resp.StatusCode, b)
+		return &ApiError{Method: req.Method, Url: req.URL.String(), StatusCode: resp.StatusCode, Body: b}
 	}
 
 	if v != nil {
@@ -34,10 +34,33 @@ func (k *Client) do(req *http.Request, v interface{}) error {
 	return nil
 }
 
+// doRaw is like do but returns the response body as is, for endpoints that don't return JSON.
+// Bodies longer than limit bytes are an error.
+func (k *Client) doRaw(req *http.Request, limit int64) ([]byte, string, error) {
+	resp, err := k.Client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if int64(len(b)) > limit {
+		return nil, "", fmt.Errorf("response from %s is larger than %d bytes", req.URL, limit)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", &ApiError{Method: req.Method, Url: req.URL.String(), StatusCode: resp.StatusCode, Body: b}
+	}
+	return b, resp.Header.Get("Content-Type"), nil
+}
+
 // newRequest creates an http.Request. A relative URL is resolved relative to the BaseURL of the Client.
 // Relative URLs should always be specified with a preceding slash.
 // If specified, the value pointed to by body is JSON encoded and included as the request body.
-func (k *Client) newRequest(method, urlStr string, body interface{}) (*http.Request, error) {
+func (k *Client) newRequest(ctx context.Context, method, urlStr string, body interface{}) (*http.Request, error) {
 
 	parsedUrl, err := k.BaseUrl.Parse(urlStr)
 	if err != nil {
@@ -57,7 +80,7 @@ func (k *Client) newRequest(method, urlStr string, body interface{}) (*http.Requ
 
 	log.Printf("%s %s %s", method, parsedUrl.String(), buf)
 
-	req, err := http.NewRequest(method, parsedUrl.String(), buf)
+	req, err := http.NewRequestWithContext(ctx, method, parsedUrl.String(), buf)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,142 @@
+package kik
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+)
+
+// Handler responds to a single message received by a bot.
+// The Client is the one the message was received for, use it to reply.
+type Handler interface {
+	HandleMessage(ctx context.Context, c *Client, m Receive) error
+}
+
+// HandlerFunc lets an ordinary function be used as a Handler.
+type HandlerFunc func(ctx context.Context, c *Client, m Receive) error
+
+// HandleMessage calls f(ctx, c, m).
+func (f HandlerFunc) HandleMessage(ctx context.Context, c *Client, m Receive) error {
+	return f(ctx, c, m)
+}
+
+// Webhook is an http.Handler for the endpoint Kik delivers messages to.
+// It verifies the request signature, decodes the payload and calls Handler once per message.
+// If Handler returns an error the request fails with a 500 so Kik delivers it again.
+//
+// With Async set the request is acknowledged as soon as the messages are queued, keyed by ChatId so
+// each chat is still handled in order. Handler errors are then only logged, as Kik already has its 200.
+// When the queue is full the request fails with a 503 and Kik delivers it again, set Dedupe so
+// the messages of the batch that were queued aren't handled twice.
+// For more on receiving messages see the [docs](https://dev.kik.com/#/docs/messaging#receiving-messages).
+type Webhook struct {
+	Client  *Client
+	Handler Handler
+
+	Dedupe *Deduplicator // Optional, skips messages that were already handled.
+	Async  Executor      // Optional, handles messages in the background, a WorkerPool or KeyedExecutor.
+	Replay *ReplayGuard  // Optional, rejects requests that were replayed or are too old.
+}
+
+// NewWebhook is a simple convenience constructor for a Webhook, you do not have to use it.
+func NewWebhook(client *Client, handler Handler) *Webhook {
+	return &Webhook{Client: client, Handler: handler}
+}
+
+func (wh *Webhook) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	signature := r.Header.Get(SignatureHeader)
+	if !wh.Client.VerifySignature(signature, body) {
+		http.Error(w, InvalidSignatureError.Error(), http.StatusForbidden)
+		return
+	}
+
+	var messages ReceivedMessages
+	if err := json.Unmarshal(body, &messages); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if wh.Replay != nil {
+		if err := wh.Replay.Check(r.Context(), signature, body, messages); err != nil {
+			status := http.StatusForbidden
+			if !errors.Is(err, ReplayError) && !errors.Is(err, StaleMessageError) {
+				status = http.StatusInternalServerError
+			}
+			http.Error(w, err.Error(), status)
+			return
+		}
+	}
+
+	if wh.Async != nil {
+		for _, m := range messages {
+			m := m
+			err := wh.Async.Submit(r.Context(), m.Common().ChatId, func(ctx context.Context) {
+				if err := wh.handle(ctx, m); err != nil {
+					log.Printf("error handling %T for %s: %v", m, wh.Client.BotUsername, err)
+				}
+			})
+			if err != nil {
+				wh.forgetRequest(r.Context(), signature, body)
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	for _, m := range messages {
+		if err := wh.handle(r.Context(), m); err != nil {
+			log.Printf("error handling %T for %s: %v", m, wh.Client.BotUsername, err)
+			wh.forgetRequest(r.Context(), signature, body)
+			http.Error(w, "error handling message", http.StatusInternalServerError)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// handle passes a single message to the Handler, unless it is a duplicate.
+func (wh *Webhook) handle(ctx context.Context, m Receive) error {
+	if wh.Dedupe != nil {
+		dup, err := wh.Dedupe.Duplicate(ctx, m)
+		if err != nil {
+			return err
+		}
+		if dup {
+			return nil
+		}
+	}
+
+	err := wh.Handler.HandleMessage(ctx, wh.Client, m)
+	if err != nil && wh.Dedupe != nil {
+		if forgetErr := wh.Dedupe.Forget(ctx, m); forgetErr != nil {
+			log.Printf("could not forget %s, it won't be handled again: %v", m.Common().Id, forgetErr)
+		}
+	}
+	return err
+}
+
+// forgetRequest lets the ReplayGuard accept the request again when Kik redelivers it after a failure.
+func (wh *Webhook) forgetRequest(ctx context.Context, signature string, body []byte) {
+	if wh.Replay == nil {
+		return
+	}
+	if err := wh.Replay.Forget(ctx, signature, body); err != nil {
+		log.Printf("could not forget request, its redelivery will be rejected: %v", err)
+	}
+}
@@ -0,0 +1,162 @@
+package kik_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/4kelly/go-kik/kik"
+	"github.com/4kelly/go-kik/kiktest"
+)
+
+func TestWebhook_SkipsRedeliveredMessages(t *testing.T) {
+	client, _, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	handled := 0
+	fail := true
+	wh := kik.NewWebhook(client, kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		handled++
+		if fail {
+			fail = false
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	}))
+	wh.Dedupe = kik.NewDeduplicator(time.Hour, 100)
+
+	// The first delivery fails, so Kik redelivers. The second succeeds, the third is a duplicate.
+	wantStatus := []int{http.StatusInternalServerError, http.StatusOK, http.StatusOK}
+	for i, want := range wantStatus {
+		w := httptest.NewRecorder()
+		wh.ServeHTTP(w, kiktest.WebhookRequest(client, textPayload))
+		if w.Code != want {
+			t.Errorf("delivery %d: ServeHTTP() status = %d; want %d", i+1, w.Code, want)
+		}
+	}
+
+	if handled != 2 || wh.Dedupe.Duplicates() != 1 {
+		t.Errorf("handled %d, %d duplicates; want 2 handled, 1 duplicate", handled, wh.Dedupe.Duplicates())
+	}
+}
+
+func TestMemorySeenStore_ForgetsAfterWindowAndWhenFull(t *testing.T) {
+	store := kik.NewMemorySeenStore(2)
+	ctx := context.Background()
+
+	seen := func(id string, window time.Duration) bool {
+		ok, _ := store.MarkSeen(ctx, id, window)
+		return ok
+	}
+
+	_ = seen("a", time.Hour)
+	if !seen("a", time.Hour) {
+		t.Errorf("MarkSeen(a) = false; want true within the window")
+	}
+	if seen("a", 0) {
+		t.Errorf("MarkSeen(a) = true; want false outside the window")
+	}
+	_ = seen("b", time.Hour)
+	_ = seen("c", time.Hour)
+	if seen("a", time.Hour) {
+		t.Errorf("MarkSeen(a) = true; want false after being evicted")
+	}
+}
+
+func TestWebhook_AsyncAcknowledgesAndDrainsInOrder(t *testing.T) {
+	client, _, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var handled []string
+	wh := kik.NewWebhook(client, kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		started <- struct{}{}
+		<-release
+		handled = append(handled, m.Common().Id)
+		return nil
+	}))
+	wh.Async = kik.NewWorkerPool(1, 1)
+
+	deliver := func(id string) int {
+		w := httptest.NewRecorder()
+		payload := `{"messages": [{"type": "text", "chatId": "c1", "id": "` + id + `", "from": "alice", "body": "hi"}]}`
+		wh.ServeHTTP(w, kiktest.WebhookRequest(client, payload))
+		return w.Code
+	}
+
+	// m1 is acknowledged while the handler is still busy with it, m2 fills the queue and m3 is turned away.
+	if code := deliver("m1"); code != http.StatusOK {
+		t.Fatalf("delivering m1: status = %d; want %d", code, http.StatusOK)
+	}
+	<-started
+	if code := deliver("m2"); code != http.StatusOK {
+		t.Errorf("delivering m2: status = %d; want %d", code, http.StatusOK)
+	}
+	if code := deliver("m3"); code != http.StatusServiceUnavailable {
+		t.Errorf("delivering m3: status = %d; want %d", code, http.StatusServiceUnavailable)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := wh.Async.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if len(handled) != 2 || handled[0] != "m1" || handled[1] != "m2" {
+		t.Errorf("handled %v; want [m1 m2]", handled)
+	}
+	if err := wh.Async.Submit(ctx, "c1", func(context.Context) {}); !errors.Is(err, kik.PoolClosedError) {
+		t.Errorf("Submit() after Shutdown error = %v; want %v", err, kik.PoolClosedError)
+	}
+}
+
+func TestWebhook_ReplayGuardRejectsReplaysAndStaleMessages(t *testing.T) {
+	client, _, teardown := kiktest.TestClient(t)
+	defer teardown()
+
+	fail := true
+	wh := kik.NewWebhook(client, kik.HandlerFunc(func(ctx context.Context, c *kik.Client, m kik.Receive) error {
+		if fail {
+			fail = false
+			return errors.New("downstream unavailable")
+		}
+		return nil
+	}))
+	var rejected []error
+	wh.Replay = kik.NewReplayGuard(time.Minute, 100)
+	wh.Replay.OnReject = func(err error) { rejected = append(rejected, err) }
+
+	payload := func(sent time.Time) string {
+		return fmt.Sprintf(`{"messages": [{"type": "text", "chatId": "c1", "id": "m1", "from": "alice", "body": "hi", "timestamp": %d}]}`,
+			sent.UnixNano()/int64(time.Millisecond))
+	}
+	fresh := payload(time.Now())
+
+	// The failed delivery may be redelivered, the successful one may not.
+	deliveries := []struct {
+		body string
+		want int
+	}{
+		{fresh, http.StatusInternalServerError},
+		{fresh, http.StatusOK},
+		{fresh, http.StatusForbidden},
+		{payload(time.Now().Add(-time.Hour)), http.StatusForbidden},
+	}
+	for i, d := range deliveries {
+		w := httptest.NewRecorder()
+		wh.ServeHTTP(w, kiktest.WebhookRequest(client, d.body))
+		if w.Code != d.want {
+			t.Errorf("delivery %d: ServeHTTP() status = %d; want %d", i+1, w.Code, d.want)
+		}
+	}
+
+	if len(rejected) != 2 || !errors.Is(rejected[0], kik.ReplayError) || !errors.Is(rejected[1], kik.StaleMessageError) {
+		t.Errorf("OnReject got %v; want a replay then a stale message", rejected)
+	}
+}
@@ -0,0 +1,126 @@
+package kik
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+var QueueFullError = errors.New("worker queue is full")
+var PoolClosedError = errors.New("worker pool is shut down")
+
+// WorkerPool runs tasks in the background on a fixed number of workers with bounded queues.
+// Tasks with the same key always run on the same worker, so they run one at a time in the order
+// they were submitted. The Webhook uses the ChatId as key to keep each conversation in order.
+type WorkerPool struct {
+	// EnqueueTimeout is how long Submit waits for room in a full queue before returning QueueFullError.
+	// Zero fails straight away, which lets Kik redeliver the message later rather than holding the request.
+	EnqueueTimeout time.Duration
+
+	queues []chan func(context.Context)
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu      sync.RWMutex
+	closed  bool
+	workers sync.WaitGroup
+}
+
+// NewWorkerPool starts workers, each with a queue of queueSize tasks.
+// Defaults are 8 workers and 100 tasks for values that aren't positive.
+func NewWorkerPool(workers, queueSize int) *WorkerPool {
+	if workers <= 0 {
+		workers = 8
+	}
+	if queueSize <= 0 {
+		queueSize = 100
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	p := &WorkerPool{queues: make([]chan func(context.Context), workers), ctx: ctx, cancel: cancel}
+	for i := range p.queues {
+		p.queues[i] = make(chan func(context.Context), queueSize)
+		p.workers.Add(1)
+		go p.work(p.queues[i])
+	}
+	return p
+}
+
+func (p *WorkerPool) work(queue chan func(context.Context)) {
+	defer p.workers.Done()
+	for task := range queue {
+		task(p.ctx)
+	}
+}
+
+// Submit queues a task. The task's context is cancelled if Shutdown gives up waiting for it.
+func (p *WorkerPool) Submit(ctx context.Context, key string, task func(ctx context.Context)) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return PoolClosedError
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	queue := p.queues[h.Sum32()%uint32(len(p.queues))]
+
+	select {
+	case queue <- task:
+		return nil
+	default:
+	}
+	if p.EnqueueTimeout <= 0 {
+		return QueueFullError
+	}
+
+	timer := time.NewTimer(p.EnqueueTimeout)
+	defer timer.Stop()
+	select {
+	case queue <- task:
+		return nil
+	case <-timer.C:
+		return QueueFullError
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Len returns the number of queued tasks, not counting the ones running.
+func (p *WorkerPool) Len() int {
+	n := 0
+	for _, q := range p.queues {
+		n += len(q)
+	}
+	return n
+}
+
+// Shutdown stops accepting tasks and waits for the queued ones to finish.
+// If ctx is done first, running tasks have their context cancelled and ctx.Err() is returned.
+func (p *WorkerPool) Shutdown(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		for _, q := range p.queues {
+			close(q)
+		}
+	}
+	p.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		p.workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		p.cancel()
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,68 @@
+package kiktest
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// MessageRecorder records the messages sent to a mocked Kik API.
+type MessageRecorder struct {
+	mu       sync.Mutex
+	messages []map[string]interface{}
+	requests int
+}
+
+// RecordMessages mocks the send message and broadcast endpoints on mux and records every message sent to them.
+// Messages are decoded into maps so tests can check any field, whatever the message type.
+func RecordMessages(mux *http.ServeMux) *MessageRecorder {
+	rec := &MessageRecorder{}
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var payload struct {
+			Messages []map[string]interface{} `json:"messages"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rec.mu.Lock()
+		rec.messages = append(rec.messages, payload.Messages...)
+		rec.requests++
+		rec.mu.Unlock()
+
+		w.Write([]byte("{}"))
+	}
+	mux.HandleFunc(kik.SendMessageUrl, handler)
+	mux.HandleFunc(kik.BroadcastUrl, handler)
+	return rec
+}
+
+// Messages returns every message recorded so far, in the order they were sent.
+func (r *MessageRecorder) Messages() []map[string]interface{} {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	out := make([]map[string]interface{}, len(r.messages))
+	copy(out, r.messages)
+	return out
+}
+
+// Bodies returns the body of every recorded message, "" for messages without one.
+func (r *MessageRecorder) Bodies() []string {
+	var bodies []string
+	for _, m := range r.Messages() {
+		b, _ := m["body"].(string)
+		bodies = append(bodies, b)
+	}
+	return bodies
+}
+
+// Requests returns the number of send or broadcast requests made.
+func (r *MessageRecorder) Requests() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.requests
+}
@@ -0,0 +1,24 @@
+package kiktest
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/4kelly/go-kik/kik"
+)
+
+// WebhookRequest builds a webhook request as Kik would send it to the bot behind c, signed with its API key.
+func WebhookRequest(c *kik.Client, body string) *http.Request {
+	h := hmac.New(sha1.New, []byte(c.ApiKey))
+	h.Write([]byte(body))
+
+	req := httptest.NewRequest(http.MethodPost, "/incoming", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(kik.UsernameHeader, c.BotUsername)
+	req.Header.Set(kik.SignatureHeader, hex.EncodeToString(h.Sum(nil)))
+	return req
+}
@@ -4,12 +4,12 @@
 package system
 
 import (
-	"fmt"
+	"bytes"
+	"context"
 	"github.com/4kelly/go-kik/kik"
 	"github.com/google/go-cmp/cmp"
-	"io/ioutil"
+	"image/png"
 	"log"
-	"net/http"
 	"os"
 	"testing"
 )
@@ -114,18 +114,13 @@ func TestCreateCode_HappyPath(t *testing.T) {
 		t.Errorf("Error while trying create a Kik scan code. %v.", err)
 	}
 
-	url := fmt.Sprintf("https://api.kik.com/v1/code/%s?c=1", code.Id)
-	codeID, err := http.Get(url)
+	img, err := kikClient.GetCodeImage(context.Background(), code.Id, kik.CodeColorTurquoise)
 	if err != nil {
 		t.Errorf("Error while trying to get scan code image. %v.", err)
 	}
 
-	defer codeID.Body.Close()
-	body, _ := ioutil.ReadAll(codeID.Body)
-	contentType := http.DetectContentType(body)
-
-	if contentType != "image/png" {
-		t.Errorf("Returned data must be an image.")
+	if _, err := png.Decode(bytes.NewReader(img)); err != nil {
+		t.Errorf("Returned data must be an image. %v.", err)
 	}
 }
 